@@ -0,0 +1,46 @@
+package clients
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+const defaultAzureApiVersion = "2024-10-21"
+
+// getAzureCompletionsUrl builds the url of a deployment. Azure serves every deployment
+// under its own path and requires the api version in the query
+func (c OpenAiClient) getAzureCompletionsUrl(deployment string) string {
+	apiVersion := c.azure.ApiVersion
+	if apiVersion == "" {
+		apiVersion = defaultAzureApiVersion
+	}
+
+	return fmt.Sprintf(
+		"%s/openai/deployments/%s/chat/completions?api-version=%s",
+		getAzureBaseUrl(c.apiUrl),
+		url.PathEscape(deployment),
+		url.QueryEscape(apiVersion))
+}
+
+// deployments can't be listed with the api key, so they come from the config
+func (c OpenAiClient) getAzureDeployments() ProcessModelsResponse {
+	deployments := []string{}
+	for deployment := range c.azure.Deployments {
+		deployments = append(deployments, deployment)
+	}
+
+	if len(deployments) == 0 {
+		return ProcessModelsResponse{
+			Err: fmt.Errorf("No Azure deployments configured. Add them to the `azure.deployments` section of the config"),
+		}
+	}
+	slices.Sort(deployments)
+
+	models := ModelsListResponse{Object: "list"}
+	for _, deployment := range deployments {
+		models.Data = append(models.Data, ModelDescription{Id: deployment})
+	}
+
+	return ProcessModelsResponse{Result: models}
+}
@@ -0,0 +1,84 @@
+package clients
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	chatCompletionsPath = "/chat/completions"
+	modelsPath          = "/models"
+	defaultApiVersion   = "/v1"
+	modelPlaceholder    = "{model}"
+)
+
+// matches version segments like `/v1` or `/v1beta` at the end of the path
+var apiVersionSuffix = regexp.MustCompile(`/v\d+[a-z0-9]*$`)
+
+// getBaseUrl returns the api root of the configured url, keeping its path and query,
+// so that gateways served under a path prefix work:
+//
+//	https://api.openai.com/v1/chat/completions -> https://api.openai.com/v1
+//	https://gw.internal/llm/openai/v1          -> https://gw.internal/llm/openai/v1
+//	http://localhost:11434                     -> http://localhost:11434/v1
+func getBaseUrl(configUrl string) *url.URL {
+	parsedUrl, err := url.Parse(configUrl)
+	if err != nil {
+		util.Log("Failed to parse openAi api url from config", err)
+		return &url.URL{}
+	}
+
+	path := strings.TrimSuffix(parsedUrl.Path, "/")
+	path = strings.TrimSuffix(path, chatCompletionsPath)
+	if !apiVersionSuffix.MatchString(path) {
+		path += defaultApiVersion
+	}
+
+	parsedUrl.Path = path
+	parsedUrl.RawPath = ""
+	return parsedUrl
+}
+
+// getAzureBaseUrl returns the resource url, everything from `/openai` on is built per deployment
+func getAzureBaseUrl(configUrl string) *url.URL {
+	parsedUrl, err := url.Parse(configUrl)
+	if err != nil {
+		util.Log("Failed to parse azure api url from config", err)
+		return &url.URL{}
+	}
+
+	path, _, _ := strings.Cut(parsedUrl.Path, "/openai")
+	parsedUrl.Path = strings.TrimSuffix(path, "/")
+	parsedUrl.RawPath = ""
+	parsedUrl.RawQuery = ""
+	return parsedUrl
+}
+
+func joinUrl(base *url.URL, path string) string {
+	joined := *base
+	joined.Path += path
+	return joined.String()
+}
+
+// getCompletionsUrl prefers the configured override. Azure picks the model
+// by the deployment in the url, everything else by the model in the body
+func (c OpenAiClient) getCompletionsUrl(model string) string {
+	if c.endpoints.ChatCompletions != "" {
+		return strings.ReplaceAll(c.endpoints.ChatCompletions, modelPlaceholder, url.PathEscape(model))
+	}
+
+	if c.provider == util.Azure {
+		return c.getAzureCompletionsUrl(model)
+	}
+	return joinUrl(getBaseUrl(c.apiUrl), chatCompletionsPath)
+}
+
+func (c OpenAiClient) getModelsUrl() string {
+	if c.endpoints.Models != "" {
+		return c.endpoints.Models
+	}
+	return joinUrl(getBaseUrl(c.apiUrl), modelsPath)
+}
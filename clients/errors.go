@@ -0,0 +1,189 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tearingItUp786/nekot/util"
+)
+
+type ApiErrorKind int
+
+const (
+	UnknownApiError ApiErrorKind = iota
+	InvalidApiKeyError
+	PermissionDeniedError
+	ModelNotFoundError
+	ContextLengthExceededError
+	QuotaExceededError
+	RateLimitError
+	ServerError
+)
+
+// ApiError is an error response of a provider, parsed from one of the known envelopes
+type ApiError struct {
+	Kind       ApiErrorKind
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+}
+
+var apiErrorHints = map[ApiErrorKind]string{
+	InvalidApiKeyError:         "The API key was rejected. Check that `OPENAI_API_KEY`, `apiKeyCommand` or the stored key is a valid key of the configured provider.",
+	PermissionDeniedError:      "The API key is not allowed to perform this request. Check the permissions of the key or the project it belongs to.",
+	ModelNotFoundError:         "The model is not available. Pick another one in the settings pane, or purge the models cache with `--purge-cache`.",
+	ContextLengthExceededError: "The conversation doesn't fit into the context window of the model. Switch the context strategy with `s` in the sessions pane, lower `max_tokens` or start a new session.",
+	QuotaExceededError:         "The quota of the API key is exhausted. Check the plan and billing details of your provider account.",
+	RateLimitError:             "Rate limit of the provider was reached, wait a bit and try again.",
+	ServerError:                "The provider failed to process the request, try again later.",
+}
+
+func (e *ApiError) Error() string {
+	hint, ok := apiErrorHints[e.Kind]
+	if !ok {
+		if e.Message != "" {
+			return fmt.Sprintf("Request failed with status %d: %s", e.StatusCode, e.Message)
+		}
+		return fmt.Sprintf("Request failed with status %d", e.StatusCode)
+	}
+
+	if e.Message == "" {
+		return hint
+	}
+	return hint + "\n\nProvider response: " + e.Message
+}
+
+// errorEnvelope covers the error bodies of OpenAI compatible apis, Mistral, Anthropic and local servers:
+//
+//	OpenAI:    {"error": {"message": "...", "type": "...", "code": "..."}}
+//	Anthropic: {"type": "error", "error": {"type": "...", "message": "..."}}
+//	Mistral:   {"object": "error", "message": "...", "type": "...", "code": "..."} or {"detail": [{"msg": "..."}]}
+//	Ollama:    {"error": "..."}
+type errorEnvelope struct {
+	Error   json.RawMessage `json:"error"`
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"`
+	Detail  json.RawMessage `json:"detail"`
+}
+
+type errorDetails struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"`
+}
+
+// ParseApiError turns an error response into an ApiError. Raw body goes to the debug log
+func ParseApiError(statusCode int, body []byte) *ApiError {
+	util.Log("Api error response, status:", statusCode, "body:", string(body))
+
+	apiError := &ApiError{StatusCode: statusCode}
+
+	var envelope errorEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		apiError.Message = strings.TrimSpace(string(body))
+		apiError.Kind = classifyApiError(apiError)
+		return apiError
+	}
+
+	var details errorDetails
+	var errorText string
+	switch {
+	case json.Unmarshal(envelope.Error, &details) == nil && details.Message != "":
+		apiError.Message = details.Message
+		apiError.Type = details.Type
+		apiError.Code = rawCodeToString(details.Code)
+	case json.Unmarshal(envelope.Error, &errorText) == nil && errorText != "":
+		apiError.Message = errorText
+	default:
+		apiError.Message = envelope.Message
+		apiError.Code = rawCodeToString(envelope.Code)
+		// Anthropic puts "error" into the top level type
+		if envelope.Type != "error" {
+			apiError.Type = envelope.Type
+		}
+		if apiError.Message == "" {
+			apiError.Message = getDetailMessage(envelope.Detail)
+		}
+	}
+
+	apiError.Kind = classifyApiError(apiError)
+	return apiError
+}
+
+// codes are strings for OpenAI, but numbers or null for others
+func rawCodeToString(code json.RawMessage) string {
+	if len(code) == 0 || string(code) == "null" {
+		return ""
+	}
+
+	var text string
+	if json.Unmarshal(code, &text) == nil {
+		return text
+	}
+	return string(code)
+}
+
+func getDetailMessage(detail json.RawMessage) string {
+	var text string
+	if json.Unmarshal(detail, &text) == nil {
+		return text
+	}
+
+	var items []struct {
+		Msg string `json:"msg"`
+	}
+	if json.Unmarshal(detail, &items) != nil {
+		return ""
+	}
+
+	messages := []string{}
+	for _, item := range items {
+		messages = append(messages, item.Msg)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func classifyApiError(e *ApiError) ApiErrorKind {
+	message := strings.ToLower(e.Message)
+
+	switch {
+	case e.Code == "context_length_exceeded" ||
+		strings.Contains(message, "context length") ||
+		strings.Contains(message, "context window") ||
+		strings.Contains(message, "prompt is too long") ||
+		strings.Contains(message, "too many tokens"):
+		return ContextLengthExceededError
+
+	case e.Code == insufficientQuotaCode ||
+		e.Type == insufficientQuotaCode ||
+		strings.Contains(message, "exceeded your current quota"):
+		return QuotaExceededError
+
+	case e.StatusCode == http.StatusUnauthorized ||
+		e.Code == "invalid_api_key" ||
+		e.Type == "authentication_error":
+		return InvalidApiKeyError
+
+	case e.StatusCode == http.StatusForbidden || e.Type == "permission_error":
+		return PermissionDeniedError
+
+	case e.Code == "model_not_found" ||
+		e.Code == "DeploymentNotFound" ||
+		e.Type == "invalid_model" ||
+		e.Type == "not_found_error" && strings.Contains(message, "model") ||
+		strings.Contains(message, "model") && (strings.Contains(message, "not found") || strings.Contains(message, "does not exist")):
+		return ModelNotFoundError
+
+	case e.StatusCode == http.StatusTooManyRequests || e.Type == "rate_limit_error":
+		return RateLimitError
+
+	case e.StatusCode >= 500 || e.Type == "overloaded_error" || e.Type == "api_error" || e.Type == "server_error":
+		return ServerError
+	}
+
+	return UnknownApiError
+}
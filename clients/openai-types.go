@@ -1,11 +1,26 @@
 package clients
 
+import "github.com/tearingItUp786/nekot/util"
+
 type Choice struct {
 	Index        int                    `json:"index"`
 	Delta        map[string]interface{} `json:"delta"`
 	FinishReason string                 `json:"finish_reason"`
 }
 
+// ToolCallDelta is a fragment of a tool call streamed in `delta.tool_calls`.
+// The first fragment carries the id and the function name, the following ones
+// only carry pieces of the arguments and must be glued together by index.
+type ToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
 type CompletionChunk struct {
 	ID               string      `json:"id"`
 	Object           string      `json:"object"`
@@ -22,6 +37,15 @@ type TokenUsage struct {
 	Total      int `json:"total_tokens"`
 }
 
+// ChatCompletion is the body of a non streamed completion
+type ChatCompletion struct {
+	Choices []struct {
+		Message      util.MessageToSend `json:"message"`
+		FinishReason string             `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *TokenUsage `json:"usage"`
+}
+
 type CompletionResponse struct {
 	Data CompletionChunk `json:"data"`
 }
@@ -44,6 +68,8 @@ type ProcessApiCompletionResponse struct {
 	Result CompletionChunk // or whatever type you need
 	Err    error
 	Final  bool
+	// set when the request failed and is about to be sent again, carries no chunk
+	Retry *util.RetryScheduled
 }
 
 type ProcessModelsResponse struct {
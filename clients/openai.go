@@ -2,18 +2,18 @@ package clients
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/secrets"
 	"github.com/tearingItUp786/nekot/util"
 )
 
@@ -21,36 +21,69 @@ type OpenAiClient struct {
 	apiUrl        string
 	systemMessage string
 	provider      util.ApiProvider
-	client        http.Client
+	client        *http.Client
+	// misconfigured network fails every request, instead of silently bypassing proxy or CA
+	clientErr      error
+	readTimeout    time.Duration
+	retryPolicy    retryPolicy
+	requestOptions requestOptions
+	azure          config.AzureConfig
+	endpoints      config.EndpointsConfig
 }
 
-func NewOpenAiClient(apiUrl, systemMessage string) *OpenAiClient {
-	provider := util.GetInferenceProvider(apiUrl)
+func NewOpenAiClient(cfg config.Config) *OpenAiClient {
+	provider := util.GetInferenceProvider(cfg.ChatGPTApiUrl)
+	client, err := getHttpClient(cfg.Network)
+	if err != nil {
+		util.Log("Failed to configure http client", err)
+	}
+
 	return &OpenAiClient{
-		provider:      provider,
-		apiUrl:        apiUrl,
-		systemMessage: systemMessage,
-		client:        http.Client{},
+		provider:       provider,
+		apiUrl:         cfg.ChatGPTApiUrl,
+		systemMessage:  cfg.SystemMessage,
+		client:         client,
+		clientErr:      err,
+		readTimeout:    getReadTimeout(cfg.Network),
+		retryPolicy:    newRetryPolicy(cfg.Retry),
+		requestOptions: newRequestOptions(cfg.Request, provider),
+		azure:          cfg.Azure,
+		endpoints:      cfg.Endpoints,
 	}
 }
 
+// WithSystemMessage returns a copy of the client that sends the given system message
+func (c OpenAiClient) WithSystemMessage(systemMessage string) *OpenAiClient {
+	c.systemMessage = systemMessage
+	return &c
+}
+
 func (c OpenAiClient) RequestCompletion(
 	ctx context.Context,
 	chatMsgs []util.MessageToSend,
 	modelSettings util.Settings,
+	toolDefinitions []util.ToolDefinition,
 	resultChan chan ProcessApiCompletionResponse,
 ) tea.Cmd {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	path := "v1/chat/completions"
+	apiKey := secrets.GetApiKey()
+	requestUrl := c.getCompletionsUrl(modelSettings.Model)
 	processResultID := 0 // Initialize a counter for ProcessResult IDs
 
 	return func() tea.Msg {
-		body, err := c.constructCompletionRequestPayload(chatMsgs, modelSettings)
+		body, err := c.constructCompletionRequestPayload(chatMsgs, modelSettings, toolDefinitions)
 		if err != nil {
 			return util.ErrorEvent{Message: err.Error()}
 		}
 
-		resp, err := c.postOpenAiAPI(ctx, apiKey, path, body)
+		// retries are reported through the result channel, so that the ui can show them
+		notifyRetry := func(attempt, maxAttempts int, delay time.Duration) {
+			resultChan <- ProcessApiCompletionResponse{
+				ID:    processResultID,
+				Retry: &util.RetryScheduled{Attempt: attempt, MaxAttempts: maxAttempts, Delay: delay},
+			}
+		}
+
+		resp, err := c.postOpenAiAPI(ctx, apiKey, requestUrl, body, notifyRetry)
 		if err != nil {
 			return util.ErrorEvent{Message: err.Error()}
 		}
@@ -60,11 +93,85 @@ func (c OpenAiClient) RequestCompletion(
 	}
 }
 
+const summaryInstructions = "Summarize the conversation below. Keep every fact, decision, code identifier " +
+	"and open question that may be needed to continue the conversation. Reply with the summary only."
+
+// RequestSummary asks the model to condense a part of the conversation into a single message
+func (c OpenAiClient) RequestSummary(
+	ctx context.Context,
+	previousSummary string,
+	chatMsgs []util.MessageToSend,
+	modelSettings util.Settings,
+) (string, *TokenUsage, error) {
+	apiKey := secrets.GetApiKey()
+	requestUrl := c.getCompletionsUrl(modelSettings.Model)
+
+	var transcript strings.Builder
+	if previousSummary != "" {
+		transcript.WriteString("summary of the earlier conversation: " + previousSummary + "\n\n")
+	}
+	for _, msg := range chatMsgs {
+		if msg.Content != "" {
+			transcript.WriteString(msg.Role + ": " + msg.Content + "\n\n")
+		}
+	}
+
+	reqParams := map[string]interface{}{
+		"model":      c.getModelName(modelSettings.Model),
+		"max_tokens": modelSettings.MaxTokens,
+		"stream":     false,
+		"messages": []util.MessageToSend{
+			ConstructUserMessage(summaryInstructions + "\n\n" + transcript.String()),
+		},
+	}
+	util.TransformRequestHeaders(c.provider, reqParams)
+	delete(reqParams, "stream_options")
+
+	body, err := json.Marshal(reqParams)
+	if err != nil {
+		return "", nil, err
+	}
+
+	resp, err := c.postOpenAiAPI(ctx, apiKey, requestUrl, body, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return "", nil, ParseApiError(resp.StatusCode, respBody)
+	}
+
+	var completion ChatCompletion
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return "", nil, err
+	}
+
+	if len(completion.Choices) == 0 {
+		return "", nil, fmt.Errorf("summary response has no choices")
+	}
+
+	return completion.Choices[0].Message.Content, completion.Usage, nil
+}
+
+func (c OpenAiClient) IsSystemMessageSupported(model string) bool {
+	return util.IsSystemMessageSupported(c.provider, c.getModelName(model))
+}
+
 func (c OpenAiClient) RequestModelsList() ProcessModelsResponse {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	path := "v1/models"
+	if c.provider == util.Azure {
+		return c.getAzureDeployments()
+	}
+
+	apiKey := secrets.GetApiKey()
+	requestUrl := c.getModelsUrl()
 
-	resp, err := c.getOpenAiAPI(apiKey, path)
+	resp, err := c.getOpenAiAPI(apiKey, requestUrl)
 	if err != nil {
 		return ProcessModelsResponse{Err: err}
 	}
@@ -86,26 +193,48 @@ func constructSystemMessage(content string) util.MessageToSend {
 	}
 }
 
-func (c OpenAiClient) constructCompletionRequestPayload(chatMsgs []util.MessageToSend, modelSettings util.Settings) ([]byte, error) {
+func (c OpenAiClient) constructCompletionRequestPayload(
+	chatMsgs []util.MessageToSend,
+	modelSettings util.Settings,
+	toolDefinitions []util.ToolDefinition,
+) ([]byte, error) {
 	messages := []util.MessageToSend{}
-	if util.IsSystemMessageSupported(c.provider, modelSettings.Model) {
+	if c.IsSystemMessageSupported(modelSettings.Model) {
 		messages = append(messages, constructSystemMessage(c.systemMessage))
 	}
 
 	for _, singleMessage := range chatMsgs {
-		if singleMessage.Content != "" {
+		// reasoning is only kept for display purposes, models don't expect it back
+		singleMessage.Reasoning = ""
+
+		// assistant messages with tool calls and tool results must be kept even if empty,
+		// otherwise the api rejects the conversation as incomplete
+		if singleMessage.Content != "" || len(singleMessage.ToolCalls) > 0 || singleMessage.ToolCallID != "" {
 			messages = append(messages, singleMessage)
 		}
 	}
 	log.Println("Constructing message: ", modelSettings.Model)
 
 	reqParams := map[string]interface{}{
-		"model":             modelSettings.Model, // Use string literals for keys
+		"model":             c.getModelName(modelSettings.Model), // Use string literals for keys
 		"frequency_penalty": modelSettings.Frequency,
 		"max_tokens":        modelSettings.MaxTokens,
 		"stream":            true,
 		"messages":          messages,
 	}
+
+	if modelSettings.ReasoningEffort != "" {
+		reqParams["reasoning_effort"] = modelSettings.ReasoningEffort
+	}
+
+	if modelSettings.Temperature != nil {
+		reqParams["temperature"] = *modelSettings.Temperature
+	}
+
+	if len(toolDefinitions) > 0 {
+		reqParams["tools"] = toolDefinitions
+	}
+
 	util.TransformRequestHeaders(c.provider, reqParams)
 
 	body, err := json.Marshal(reqParams)
@@ -117,44 +246,44 @@ func (c OpenAiClient) constructCompletionRequestPayload(chatMsgs []util.MessageT
 	return body, nil
 }
 
-func getBaseUrl(configUrl string) string {
-	parsedUrl, err := url.Parse(configUrl)
-	if err != nil {
-		util.Log("Failed to parse openAi api url from config")
+// getModelName resolves Azure deployments, so that model specific request params are set correctly
+func (c OpenAiClient) getModelName(model string) string {
+	if c.provider == util.Azure {
+		return c.azure.GetModelName(model)
 	}
-	baseUrl := fmt.Sprintf("%s://%s", parsedUrl.Scheme, parsedUrl.Host)
-	return baseUrl
+	return model
 }
 
-func (c OpenAiClient) getOpenAiAPI(apiKey string, path string) (*http.Response, error) {
-	baseUrl := getBaseUrl(c.apiUrl)
-	requestUrl := fmt.Sprintf("%s/%s", baseUrl, path)
-
-	req, err := http.NewRequest("GET", requestUrl, nil)
+func (c OpenAiClient) getOpenAiAPI(apiKey string, requestUrl string) (*http.Response, error) {
+	requestUrl, err := c.requestOptions.withQuery(requestUrl)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	setHeaders := func(req *http.Request) {
+		c.requestOptions.setHeaders(req, apiKey)
+	}
 
-	client := &http.Client{}
-	return client.Do(req)
+	return c.doWithRetry(context.Background(), "GET", requestUrl, nil, setHeaders, nil)
 }
 
-func (c OpenAiClient) postOpenAiAPI(ctx context.Context, apiKey, path string, body []byte) (*http.Response, error) {
-	baseUrl := getBaseUrl(c.apiUrl)
-	requestUrl := fmt.Sprintf("%s/%s", baseUrl, path)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewBuffer(body))
+func (c OpenAiClient) postOpenAiAPI(
+	ctx context.Context,
+	apiKey, requestUrl string,
+	body []byte,
+	notifyRetry onRetry,
+) (*http.Response, error) {
+	requestUrl, err := c.requestOptions.withQuery(requestUrl)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	setHeaders := func(req *http.Request) {
+		req.Header.Set("Content-Type", "application/json")
+		c.requestOptions.setHeaders(req, apiKey)
+	}
 
-	client := &http.Client{}
-	return client.Do(req)
+	return c.doWithRetry(ctx, "POST", requestUrl, body, setHeaders, notifyRetry)
 }
 
 func processModelsListResponse(resp *http.Response) ProcessModelsResponse {
@@ -165,7 +294,7 @@ func processModelsListResponse(resp *http.Response) ProcessModelsResponse {
 		if err != nil {
 			return ProcessModelsResponse{Err: err}
 		}
-		return ProcessModelsResponse{Err: fmt.Errorf(string(bodyBytes))}
+		return ProcessModelsResponse{Err: ParseApiError(resp.StatusCode, bodyBytes)}
 	}
 
 	resBody, err := io.ReadAll(resp.Body)
@@ -197,7 +326,7 @@ func (c OpenAiClient) processCompletionResponse(
 			resultChan <- ProcessApiCompletionResponse{ID: *processResultID, Err: err}
 			return
 		}
-		resultChan <- ProcessApiCompletionResponse{ID: *processResultID, Err: fmt.Errorf(string(bodyBytes))}
+		resultChan <- ProcessApiCompletionResponse{ID: *processResultID, Err: ParseApiError(resp.StatusCode, bodyBytes)}
 		return
 	}
 
@@ -233,6 +362,11 @@ func processChunk(chunkData string, id int) ProcessApiCompletionResponse {
 		return ProcessApiCompletionResponse{ID: id, Result: CompletionChunk{}, Err: err}
 	}
 
+	// some providers report failures in the middle of the stream, after responding with 200
+	if len(chunk.Choices) == 0 && strings.Contains(chunkData, `"error"`) {
+		return ProcessApiCompletionResponse{ID: id, Err: ParseApiError(http.StatusOK, []byte(chunkData))}
+	}
+
 	return ProcessApiCompletionResponse{ID: id, Result: chunk, Err: nil}
 }
 
@@ -244,3 +378,64 @@ func (m ModelsListResponse) GetModelNames() []string {
 
 	return modelNames
 }
+
+// IsFinished reports whether the choice carries a finish reason that ends the stream
+func (c Choice) IsFinished() bool {
+	return c.FinishReason == "stop" ||
+		c.FinishReason == "length" ||
+		c.FinishReason == "tool_calls"
+}
+
+// GetReasoningDelta returns the reasoning part of the delta.
+// DeepSeek and most local servers use `reasoning_content`, some gateways use `reasoning`
+func (c Choice) GetReasoningDelta() string {
+	for _, field := range []string{"reasoning_content", "reasoning"} {
+		if reasoning, ok := c.Delta[field].(string); ok {
+			return reasoning
+		}
+	}
+	return ""
+}
+
+func (c Choice) GetToolCallDeltas() []ToolCallDelta {
+	rawToolCalls, ok := c.Delta["tool_calls"]
+	if !ok || rawToolCalls == nil {
+		return nil
+	}
+
+	// delta is decoded into a generic map, so round trip the tool calls to get typed values
+	toolCallsJson, err := json.Marshal(rawToolCalls)
+	if err != nil {
+		util.Log("Failed to marshal tool calls delta", err)
+		return nil
+	}
+
+	var deltas []ToolCallDelta
+	if err := json.Unmarshal(toolCallsJson, &deltas); err != nil {
+		util.Log("Failed to unmarshal tool calls delta", err)
+		return nil
+	}
+
+	return deltas
+}
+
+// MergeToolCallDeltas glues streamed tool call fragments into complete tool calls
+func MergeToolCallDeltas(toolCalls []util.ToolCall, deltas []ToolCallDelta) []util.ToolCall {
+	for _, delta := range deltas {
+		for len(toolCalls) <= delta.Index {
+			toolCalls = append(toolCalls, util.ToolCall{Type: "function"})
+		}
+
+		toolCall := &toolCalls[delta.Index]
+		if delta.ID != "" {
+			toolCall.ID = delta.ID
+		}
+		if delta.Type != "" {
+			toolCall.Type = delta.Type
+		}
+		toolCall.Function.Name += delta.Function.Name
+		toolCall.Function.Arguments += delta.Function.Arguments
+	}
+
+	return toolCalls
+}
@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+// requestOptions are the headers, query params and auth scheme added to every api request
+type requestOptions struct {
+	headers    map[string]string
+	query      map[string]string
+	authScheme string
+	authHeader string
+}
+
+func newRequestOptions(cfg config.RequestConfig, provider util.ApiProvider) requestOptions {
+	scheme := cfg.Auth.Scheme
+	if scheme == "" && provider == util.Azure {
+		scheme = config.ApiKeyAuth
+	}
+	if scheme == "" {
+		scheme = config.BearerAuth
+	}
+
+	return requestOptions{
+		headers:    cfg.Headers,
+		query:      cfg.Query,
+		authScheme: scheme,
+		authHeader: cfg.Auth.Header,
+	}
+}
+
+// setHeaders adds the auth header first, so that configured headers can override it.
+// Values are expanded with env variables to keep secrets out of the config file
+func (o requestOptions) setHeaders(req *http.Request, apiKey string) {
+	switch o.authScheme {
+	case config.BearerAuth:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	case config.ApiKeyAuth:
+		req.Header.Set("api-key", apiKey)
+	case config.HeaderAuth:
+		req.Header.Set(o.authHeader, apiKey)
+	case config.NoAuth:
+	}
+
+	for name, value := range o.headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+}
+
+// withQuery adds the configured query params, keeping the ones already present in the url
+func (o requestOptions) withQuery(requestUrl string) (string, error) {
+	if len(o.query) == 0 {
+		return requestUrl, nil
+	}
+
+	parsedUrl, err := url.Parse(requestUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid api url %q: %w", requestUrl, err)
+	}
+
+	query := parsedUrl.Query()
+	for name, value := range o.query {
+		query.Set(name, os.ExpandEnv(value))
+	}
+	parsedUrl.RawQuery = query.Encode()
+
+	return parsedUrl.String(), nil
+}
@@ -0,0 +1,283 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	defaultMaxAttempts        = 3
+	defaultInitialBackoff     = time.Millisecond * 500
+	defaultMaxBackoff         = time.Second * 8
+	defaultMaxRetryAfter      = time.Second * 60
+	defaultResponseTimeoutSec = 60
+)
+
+// quota errors come with 429 as well, but waiting won't help them
+const insufficientQuotaCode = "insufficient_quota"
+
+type retryPolicy struct {
+	maxAttempts     int
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	maxRetryAfter   time.Duration
+	responseTimeout time.Duration
+}
+
+// onRetry is called before waiting for the next attempt
+type onRetry func(attempt, maxAttempts int, delay time.Duration)
+
+func newRetryPolicy(cfg config.RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts:     defaultMaxAttempts,
+		initialBackoff:  defaultInitialBackoff,
+		maxBackoff:      defaultMaxBackoff,
+		maxRetryAfter:   defaultMaxRetryAfter,
+		responseTimeout: time.Second * defaultResponseTimeoutSec,
+	}
+
+	if cfg.MaxAttempts > 0 {
+		policy.maxAttempts = min(cfg.MaxAttempts, config.MaxRetryAttempts)
+	}
+	if cfg.InitialBackoffMs > 0 {
+		policy.initialBackoff = time.Millisecond * time.Duration(cfg.InitialBackoffMs)
+	}
+	if cfg.MaxBackoffMs > 0 {
+		policy.maxBackoff = time.Millisecond * time.Duration(cfg.MaxBackoffMs)
+	}
+	if cfg.MaxRetryAfterSec > 0 {
+		policy.maxRetryAfter = time.Second * time.Duration(cfg.MaxRetryAfterSec)
+	}
+	if cfg.ResponseTimeoutSec > 0 {
+		policy.responseTimeout = time.Second * time.Duration(cfg.ResponseTimeoutSec)
+	}
+
+	return policy
+}
+
+// doWithRetry sends the request until it succeeds, fails with a non retryable error or runs out of attempts.
+// Body is kept as bytes, since every attempt needs a fresh reader
+func (c OpenAiClient) doWithRetry(
+	ctx context.Context,
+	method string,
+	requestUrl string,
+	body []byte,
+	setHeaders func(req *http.Request),
+	notify onRetry,
+) (*http.Response, error) {
+	if c.clientErr != nil {
+		return nil, c.clientErr
+	}
+
+	policy := c.retryPolicy
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doAttempt(ctx, method, requestUrl, body, setHeaders)
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		delay, retryable := policy.getRetryDelay(attempt, resp, err)
+		if !retryable || attempt >= policy.maxAttempts {
+			return resp, err
+		}
+
+		if err != nil {
+			util.Log("Request failed, retrying in", delay, "attempt", attempt, err)
+		} else {
+			util.Log("Request failed with status", resp.StatusCode, "retrying in", delay, "attempt", attempt)
+			resp.Body.Close()
+		}
+
+		if notify != nil {
+			notify(attempt+1, policy.maxAttempts, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doAttempt sends a single request. The response timeout only covers waiting for the headers,
+// streamed answers may take much longer to complete
+func (c OpenAiClient) doAttempt(
+	ctx context.Context,
+	method string,
+	requestUrl string,
+	body []byte,
+	setHeaders func(req *http.Request),
+) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(attemptCtx, method, requestUrl, bodyReader)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	setHeaders(req)
+
+	timer := time.AfterFunc(c.retryPolicy.responseTimeout, cancel)
+	resp, err := c.client.Do(req)
+
+	if !timer.Stop() && ctx.Err() == nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, noResponseError{url: requestUrl, timeout: c.retryPolicy.responseTimeout}
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = newReadTimeoutBody(resp.Body, c.readTimeout, cancel)
+	return resp, nil
+}
+
+type noResponseError struct {
+	url     string
+	timeout time.Duration
+}
+
+func (e noResponseError) Error() string {
+	return fmt.Sprintf("no response from %s in %s", e.url, e.timeout)
+}
+
+func (e noResponseError) Timeout() bool {
+	return true
+}
+
+func (p retryPolicy) getRetryDelay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		return p.backoff(attempt), isTransientError(err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if isQuotaExceeded(resp) {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusRequestTimeout:
+	case resp.StatusCode >= 500:
+	default:
+		return 0, false
+	}
+
+	delay, ok := getServerRetryDelay(resp.Header)
+	if !ok {
+		return p.backoff(attempt), true
+	}
+
+	// waiting for minutes in the middle of a chat is worse than showing the error
+	if delay > p.maxRetryAfter {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// isTransientError tells timeouts and dropped connections from errors that another attempt won't fix,
+// such as an untrusted certificate, an invalid url or a refused connection to a mistyped host
+func isTransientError(err error) bool {
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) && timeoutErr.Timeout() {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary
+	}
+
+	// the server or a proxy closed a connection that was reused for the request
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED)
+}
+
+// backoff doubles the delay with every attempt. Jitter keeps parallel clients from retrying at once.
+// Doubling stops at maxBackoff, a shift by the attempt would overflow for high attempt counts
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.initialBackoff
+	for i := 1; i < attempt && delay < p.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.maxBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// getServerRetryDelay reads the delay requested by the server from `Retry-After`
+// or from `x-ratelimit-reset-*` of the exhausted rate limit
+func getServerRetryDelay(header http.Header) (time.Duration, bool) {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Second * time.Duration(max(seconds, 0)), true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return max(time.Until(date), 0), true
+		}
+	}
+
+	delay := time.Duration(0)
+	found := false
+	for _, limit := range []string{"requests", "tokens"} {
+		if header.Get("x-ratelimit-remaining-"+limit) != "0" {
+			continue
+		}
+
+		// OpenAI sends durations like `1s` or `6m0s`
+		reset, err := time.ParseDuration(header.Get("x-ratelimit-reset-" + limit))
+		if err != nil {
+			continue
+		}
+		delay = max(delay, reset)
+		found = true
+	}
+
+	return delay, found
+}
+
+// isQuotaExceeded peeks into the body and puts it back, so that it can still be read by the caller
+func isQuotaExceeded(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body = bufferedBody{Reader: bytes.NewReader(body), original: resp.Body}
+	if err != nil {
+		return false
+	}
+
+	return bytes.Contains(body, []byte(insufficientQuotaCode))
+}
+
+// bufferedBody replays a body that was read already, closing it still releases the request
+type bufferedBody struct {
+	io.Reader
+	original io.ReadCloser
+}
+
+func (b bufferedBody) Close() error {
+	return b.original.Close()
+}
@@ -0,0 +1,347 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tearingItUp786/nekot/config"
+)
+
+// newTestClient retries quickly, so that the tests don't wait for the default backoff
+func newTestClient(retry config.RetryConfig, network config.NetworkConfig) OpenAiClient {
+	if retry.InitialBackoffMs == 0 {
+		retry.InitialBackoffMs = 1
+	}
+	if retry.MaxBackoffMs == 0 {
+		retry.MaxBackoffMs = 5
+	}
+
+	return OpenAiClient{
+		client:      &http.Client{},
+		readTimeout: getReadTimeout(network),
+		retryPolicy: newRetryPolicy(retry),
+	}
+}
+
+// countingServer answers with the responses in order, the last one is repeated
+func countingServer(t *testing.T, responses ...func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idx := int(requests.Add(1)) - 1
+		responses[min(idx, len(responses)-1)](w)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func status(code int, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}
+}
+
+func doGet(client OpenAiClient, url string, notify onRetry) (*http.Response, error) {
+	return client.doWithRetry(context.Background(), http.MethodGet, url, nil, func(req *http.Request) {}, notify)
+}
+
+func TestRetriesTooManyRequestsAndServerErrors(t *testing.T) {
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway} {
+		t.Run(fmt.Sprint(code), func(t *testing.T) {
+			server, requests := countingServer(t, status(code, `{"error":{}}`), status(http.StatusOK, "ok"))
+			client := newTestClient(config.RetryConfig{MaxAttempts: 3}, config.NetworkConfig{})
+
+			retries := []int{}
+			resp, err := doGet(client, server.URL, func(attempt, maxAttempts int, delay time.Duration) {
+				retries = append(retries, attempt)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected 200 after retrying, got %d", resp.StatusCode)
+			}
+			if requests.Load() != 2 {
+				t.Errorf("expected 2 requests, got %d", requests.Load())
+			}
+			if len(retries) != 1 || retries[0] != 2 {
+				t.Errorf("expected to be notified about attempt 2, got %v", retries)
+			}
+		})
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	server, requests := countingServer(t, status(http.StatusBadRequest, "bad request"))
+	client := newTestClient(config.RetryConfig{MaxAttempts: 3}, config.NetworkConfig{})
+
+	resp, err := doGet(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest || requests.Load() != 1 {
+		t.Errorf("expected a single 400, got %d after %d requests", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestDoesNotRetryInsufficientQuota(t *testing.T) {
+	body := `{"error":{"code":"insufficient_quota","message":"You exceeded your current quota"}}`
+	server, requests := countingServer(t, status(http.StatusTooManyRequests, body))
+	client := newTestClient(config.RetryConfig{MaxAttempts: 3}, config.NetworkConfig{})
+
+	resp, err := doGet(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if requests.Load() != 1 {
+		t.Errorf("expected no retries, got %d requests", requests.Load())
+	}
+
+	// the body was peeked at, the caller still gets all of it for the error message
+	read, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(read) != body {
+		t.Errorf("expected the body to be kept, got %q", read)
+	}
+}
+
+func TestGivesUpAtAttemptLimit(t *testing.T) {
+	server, requests := countingServer(t, status(http.StatusServiceUnavailable, "unavailable"))
+	client := newTestClient(config.RetryConfig{MaxAttempts: 4}, config.NetworkConfig{})
+
+	resp, err := doGet(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the last 503 to be returned, got %d", resp.StatusCode)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("expected 4 attempts, got %d", requests.Load())
+	}
+}
+
+func TestMaxAttemptsIsBounded(t *testing.T) {
+	policy := newRetryPolicy(config.RetryConfig{MaxAttempts: 100})
+	if policy.maxAttempts != config.MaxRetryAttempts {
+		t.Errorf("expected maxAttempts to be bounded by %d, got %d", config.MaxRetryAttempts, policy.maxAttempts)
+	}
+}
+
+func TestBackoffStaysWithinMaxBackoff(t *testing.T) {
+	policy := newRetryPolicy(config.RetryConfig{})
+	for attempt := 1; attempt <= 200; attempt++ {
+		delay := policy.backoff(attempt)
+		if delay < 0 || delay > policy.maxBackoff {
+			t.Fatalf("attempt %d: delay %s is outside of 0..%s", attempt, delay, policy.maxBackoff)
+		}
+	}
+}
+
+func TestServerRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+		found    bool
+	}{
+		{
+			name:     "retry after seconds",
+			header:   http.Header{"Retry-After": {"7"}},
+			expected: time.Second * 7,
+			found:    true,
+		},
+		{
+			name:     "exhausted requests limit",
+			header:   http.Header{"X-Ratelimit-Remaining-Requests": {"0"}, "X-Ratelimit-Reset-Requests": {"1.5s"}},
+			expected: time.Millisecond * 1500,
+			found:    true,
+		},
+		{
+			name: "longest reset of the exhausted limits",
+			header: http.Header{
+				"X-Ratelimit-Remaining-Requests": {"0"},
+				"X-Ratelimit-Reset-Requests":     {"2s"},
+				"X-Ratelimit-Remaining-Tokens":   {"0"},
+				"X-Ratelimit-Reset-Tokens":       {"6m0s"},
+			},
+			expected: time.Minute * 6,
+			found:    true,
+		},
+		{
+			name:   "limit that is not exhausted",
+			header: http.Header{"X-Ratelimit-Remaining-Tokens": {"10"}, "X-Ratelimit-Reset-Tokens": {"2s"}},
+		},
+		{
+			name: "no headers",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			delay, found := getServerRetryDelay(test.header)
+			if delay != test.expected || found != test.found {
+				t.Errorf("expected %s %v, got %s %v", test.expected, test.found, delay, found)
+			}
+		})
+	}
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	date := time.Now().Add(time.Second * 30).UTC().Format(http.TimeFormat)
+	delay, found := getServerRetryDelay(http.Header{"Retry-After": {date}})
+	if !found || delay <= time.Second*25 || delay > time.Second*30 {
+		t.Errorf("expected about 30s, got %s %v", delay, found)
+	}
+}
+
+func TestWaitsForRetryAfter(t *testing.T) {
+	retryAfter := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	server, requests := countingServer(t, retryAfter, status(http.StatusOK, "ok"))
+	client := newTestClient(config.RetryConfig{MaxAttempts: 2}, config.NetworkConfig{})
+
+	var notifiedDelay time.Duration
+	start := time.Now()
+	resp, err := doGet(client, server.URL, func(attempt, maxAttempts int, delay time.Duration) {
+		notifiedDelay = delay
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if notifiedDelay != time.Second || time.Since(start) < time.Second {
+		t.Errorf("expected to wait the requested 1s, notified %s, waited %s", notifiedDelay, time.Since(start))
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestDoesNotWaitLongerThanMaxRetryAfter(t *testing.T) {
+	retryAfter := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	server, requests := countingServer(t, retryAfter)
+	client := newTestClient(config.RetryConfig{MaxAttempts: 3, MaxRetryAfterSec: 60}, config.NetworkConfig{})
+
+	resp, err := doGet(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || requests.Load() != 1 {
+		t.Errorf("expected the 429 without retries, got %d after %d requests", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestDoesNotRetryPermanentTransportErrors(t *testing.T) {
+	// the certificate of the test server is not trusted by the client
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	// nothing listens on the port once the server is closed
+	closedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedServer.Close()
+
+	for name, url := range map[string]string{
+		"unknown authority":  tlsServer.URL,
+		"refused connection": closedServer.URL,
+		"invalid scheme":     "ftp://example.com",
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := newTestClient(config.RetryConfig{MaxAttempts: 3}, config.NetworkConfig{})
+
+			retries := 0
+			_, err := doGet(client, url, func(attempt, maxAttempts int, delay time.Duration) {
+				retries++
+			})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if retries != 0 {
+				t.Errorf("expected no retries, got %d", retries)
+			}
+		})
+	}
+}
+
+func TestRetriesDroppedConnections(t *testing.T) {
+	dropConnection := func(w http.ResponseWriter) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}
+	server, requests := countingServer(t, dropConnection, status(http.StatusOK, "ok"))
+	client := newTestClient(config.RetryConfig{MaxAttempts: 3}, config.NetworkConfig{})
+
+	resp, err := doGet(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("expected 200 after a retry, got %d after %d requests", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestReadIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		// the server stays silent after the first chunk
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestClient(config.RetryConfig{MaxAttempts: 1}, config.NetworkConfig{ReadTimeoutSec: 1})
+	resp, err := doGet(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	start := time.Now()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil || !strings.Contains(err.Error(), "no data received") {
+		t.Fatalf("expected the read timeout error, got %v", err)
+	}
+	if !strings.Contains(string(body), "first") {
+		t.Errorf("expected the data before the timeout, got %q", body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*5 {
+		t.Errorf("expected the read to stop after about 1s, took %s", elapsed)
+	}
+}
@@ -0,0 +1,151 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	defaultConnectTimeoutSec = 30
+	defaultReadTimeoutSec    = 120
+)
+
+// every client with the same network config shares the transport and its connection pool
+var (
+	transportsM sync.Mutex
+	transports  = map[config.NetworkConfig]*http.Transport{}
+)
+
+func getHttpClient(cfg config.NetworkConfig) (*http.Client, error) {
+	transportsM.Lock()
+	defer transportsM.Unlock()
+
+	transport, ok := transports[cfg]
+	if !ok {
+		var err error
+		transport, err = newTransport(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transports[cfg] = transport
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+func newTransport(cfg config.NetworkConfig) (*http.Transport, error) {
+	connectTimeout := time.Second * defaultConnectTimeoutSec
+	if cfg.ConnectTimeoutSec > 0 {
+		connectTimeout = time.Second * time.Duration(cfg.ConnectTimeoutSec)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: time.Second * 30}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	if cfg.ProxyUrl != "" {
+		proxyUrl, err := url.Parse(cfg.ProxyUrl)
+		if err != nil || proxyUrl.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q in the network config", cfg.ProxyUrl)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	tlsConfig := &tls.Config{}
+	if cfg.CaBundlePath != "" {
+		certPool, err := loadCertPool(cfg.CaBundlePath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if cfg.InsecureSkipVerify {
+		util.Log("Warning: TLS certificate verification is disabled by the network config")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+// loadCertPool adds certificates of the bundle to the system ones, so that public hosts keep working
+func loadCertPool(path string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		certPool = x509.NewCertPool()
+	}
+
+	if !certPool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", path)
+	}
+
+	return certPool, nil
+}
+
+func getReadTimeout(cfg config.NetworkConfig) time.Duration {
+	if cfg.ReadTimeoutSec > 0 {
+		return time.Second * time.Duration(cfg.ReadTimeoutSec)
+	}
+	return time.Second * defaultReadTimeoutSec
+}
+
+// readTimeoutBody cancels the request once the server stays silent for too long.
+// Unlike a timeout of the whole request it doesn't interrupt long streamed answers
+type readTimeoutBody struct {
+	io.ReadCloser
+	timer    *time.Timer
+	timeout  time.Duration
+	cancel   context.CancelFunc
+	timedOut atomic.Bool
+}
+
+func newReadTimeoutBody(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *readTimeoutBody {
+	b := &readTimeoutBody{
+		ReadCloser: body,
+		timeout:    timeout,
+		cancel:     cancel,
+	}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		cancel()
+	})
+	b.timer.Stop()
+	return b
+}
+
+// only time spent waiting for the server counts, not the time the caller takes between reads
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+
+	if err != nil && b.timedOut.Load() {
+		return n, fmt.Errorf("no data received from the server in %s", b.timeout)
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
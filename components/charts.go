@@ -0,0 +1,47 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var sparklineLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders one column per value, scaled to the largest value
+func Sparkline(values []int, style lipgloss.Style) string {
+	maxValue := 0
+	for _, value := range values {
+		maxValue = max(maxValue, value)
+	}
+
+	var sb strings.Builder
+	for _, value := range values {
+		level := 0
+		if maxValue > 0 && value > 0 {
+			// non zero values are always visible above the baseline
+			level = max(1, value*(len(sparklineLevels)-1)/maxValue)
+		}
+		sb.WriteRune(sparklineLevels[level])
+	}
+
+	return style.Render(sb.String())
+}
+
+// Bar renders a horizontal bar of the given width, filled in proportion to value/maxValue
+func Bar(value, maxValue, width int, filledStyle, emptyStyle lipgloss.Style) string {
+	if width <= 0 {
+		return ""
+	}
+
+	filled := 0
+	if maxValue > 0 {
+		filled = value * width / maxValue
+	}
+	if value > 0 {
+		filled = max(filled, 1)
+	}
+	filled = min(filled, width)
+
+	return filledStyle.Render(strings.Repeat("█", filled)) + emptyStyle.Render(strings.Repeat("░", width-filled))
+}
@@ -0,0 +1,219 @@
+package components
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+type paletteKeyMap struct {
+	up    key.Binding
+	down  key.Binding
+	run   key.Binding
+	close key.Binding
+}
+
+var defaultPaletteKeyMap = paletteKeyMap{
+	up:    key.NewBinding(key.WithKeys("up", "ctrl+k"), key.WithHelp("↑/ctrl+k", "previous command")),
+	down:  key.NewBinding(key.WithKeys("down", "ctrl+j"), key.WithHelp("↓/ctrl+j", "next command")),
+	run:   key.NewBinding(key.WithKeys(tea.KeyEnter.String()), key.WithHelp("enter", "run command")),
+	close: key.NewBinding(key.WithKeys(tea.KeyEsc.String()), key.WithHelp("esc", "close palette")),
+}
+
+func init() {
+	util.RegisterKeyBindings(util.PaletteKeys, util.KeyBindings{
+		"up":    &defaultPaletteKeyMap.up,
+		"down":  &defaultPaletteKeyMap.down,
+		"run":   &defaultPaletteKeyMap.run,
+		"close": &defaultPaletteKeyMap.close,
+	})
+}
+
+// Command is an entry of the palette, Key is the shortcut of the same action if there is one
+type Command struct {
+	ID    string
+	Title string
+	Key   string
+}
+
+type commandMatch struct {
+	command        Command
+	matchedIndexes []int
+}
+
+// CommandPalette filters the commands with a fuzzy search on their titles.
+// Running and closing is left to the owner, it asks for the selected command
+type CommandPalette struct {
+	input    textinput.Model
+	commands []Command
+	matches  []commandMatch
+	cursor   int
+	keys     paletteKeyMap
+	colors   util.SchemeColors
+	width    int
+	height   int
+}
+
+func NewCommandPalette(commands []Command, colors util.SchemeColors) CommandPalette {
+	input := textinput.New()
+	input.Placeholder = "Type a command"
+	input.Prompt = "> "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
+	input.Focus()
+
+	palette := CommandPalette{
+		input:    input,
+		commands: commands,
+		keys:     defaultPaletteKeyMap,
+		colors:   colors,
+	}
+	palette.filter()
+	return palette
+}
+
+// SetPlaceholder describes what the palette lists, e.g. when it is used as a picker
+func (p *CommandPalette) SetPlaceholder(placeholder string) {
+	p.input.Placeholder = placeholder
+}
+
+func (p *CommandPalette) SetSize(w, h int) {
+	p.width = w
+	p.height = h
+}
+
+func (p CommandPalette) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (p CommandPalette) Update(msg tea.Msg) (CommandPalette, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, p.keys.up):
+			if p.cursor > 0 {
+				p.cursor--
+			}
+			return p, nil
+
+		case key.Matches(msg, p.keys.down):
+			if p.cursor < len(p.matches)-1 {
+				p.cursor++
+			}
+			return p, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	previousQuery := p.input.Value()
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != previousQuery {
+		p.filter()
+	}
+
+	return p, cmd
+}
+
+// filter keeps the command order for an empty query, otherwise the best matches go first
+func (p *CommandPalette) filter() {
+	p.cursor = 0
+	p.matches = []commandMatch{}
+
+	query := p.input.Value()
+	if query == "" {
+		for _, command := range p.commands {
+			p.matches = append(p.matches, commandMatch{command: command})
+		}
+		return
+	}
+
+	titles := []string{}
+	for _, command := range p.commands {
+		titles = append(titles, command.Title)
+	}
+
+	for _, match := range fuzzy.Find(query, titles) {
+		p.matches = append(p.matches, commandMatch{
+			command:        p.commands[match.Index],
+			matchedIndexes: match.MatchedIndexes,
+		})
+	}
+}
+
+func (p CommandPalette) IsRunKey(msg tea.KeyMsg) bool {
+	return key.Matches(msg, p.keys.run)
+}
+
+func (p CommandPalette) IsCloseKey(msg tea.KeyMsg) bool {
+	return key.Matches(msg, p.keys.close)
+}
+
+func (p CommandPalette) GetSelectedCommand() (Command, bool) {
+	if p.cursor >= len(p.matches) {
+		return Command{}, false
+	}
+	return p.matches[p.cursor].command, true
+}
+
+func (p CommandPalette) HelpKeys() []key.Binding {
+	return []key.Binding{p.keys.up, p.keys.down, p.keys.run, p.keys.close}
+}
+
+func (p CommandPalette) View() string {
+	rows := []string{p.input.View(), ""}
+
+	// the list scrolls, so that the cursor stays visible
+	visibleRows := max(p.height-len(rows), 1)
+	offset := max(p.cursor-visibleRows+1, 0)
+
+	for i := offset; i < len(p.matches) && i < offset+visibleRows; i++ {
+		rows = append(rows, p.commandView(p.matches[i], i == p.cursor))
+	}
+
+	if len(p.matches) == 0 {
+		rows = append(rows, lipgloss.NewStyle().Foreground(p.colors.NormalTabBorderColor).Render("No matches"))
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+func (p CommandPalette) commandView(match commandMatch, isSelected bool) string {
+	titleStyle := lipgloss.NewStyle().Foreground(p.colors.DefaultTextColor)
+	prefix := "  "
+	if isSelected {
+		titleStyle = titleStyle.Foreground(p.colors.AccentColor).Bold(true)
+		prefix = "> "
+	}
+	matchStyle := titleStyle.Copy().Foreground(p.colors.HighlightColor).Underline(true)
+
+	// long titles, e.g. prompts of the history, are cut to a single row
+	titleRunes := []rune(match.command.Title)
+	if maxTitleLength := p.width - len(prefix); p.width > 0 && len(titleRunes) > maxTitleLength {
+		titleRunes = append(titleRunes[:max(maxTitleLength-1, 0)], '…')
+	}
+
+	var title strings.Builder
+	for i, char := range titleRunes {
+		style := titleStyle
+		if slices.Contains(match.matchedIndexes, i) {
+			style = matchStyle
+		}
+		title.WriteString(style.Render(string(char)))
+	}
+
+	row := titleStyle.Render(prefix) + title.String()
+	if match.command.Key == "" {
+		return row
+	}
+
+	shortcut := lipgloss.NewStyle().Foreground(p.colors.NormalTabBorderColor).Render(match.command.Key)
+	gap := p.width - lipgloss.Width(row) - lipgloss.Width(shortcut)
+	if gap < 1 {
+		return row
+	}
+	return row + strings.Repeat(" ", gap) + shortcut
+}
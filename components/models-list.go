@@ -59,6 +59,15 @@ func (l *ModelsList) GetSelectedItem() (ModelsListItem, bool) {
 	return item, ok
 }
 
+func (l *ModelsList) Select(index int) {
+	l.list.Select(index)
+}
+
+// SetItemName replaces `model` in the status bar, when the list is used for other values
+func (l *ModelsList) SetItemName(singular, plural string) {
+	l.list.SetStatusBarItemName(singular, plural)
+}
+
 func (l ModelsList) Update(msg tea.Msg) (ModelsList, tea.Cmd) {
 	var cmd tea.Cmd
 	l.list, cmd = l.list.Update(msg)
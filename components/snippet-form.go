@@ -0,0 +1,157 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tearingItUp786/nekot/snippets"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+type snippetFormKeyMap struct {
+	next     key.Binding
+	previous key.Binding
+	submit   key.Binding
+	close    key.Binding
+}
+
+var defaultSnippetFormKeyMap = snippetFormKeyMap{
+	next:     key.NewBinding(key.WithKeys("down", "ctrl+j"), key.WithHelp("↓/ctrl+j", "next field")),
+	previous: key.NewBinding(key.WithKeys("up", "ctrl+k"), key.WithHelp("↑/ctrl+k", "previous field")),
+	submit:   key.NewBinding(key.WithKeys(tea.KeyEnter.String()), key.WithHelp("enter", "next field or insert")),
+	close:    key.NewBinding(key.WithKeys(tea.KeyEsc.String()), key.WithHelp("esc", "cancel")),
+}
+
+func init() {
+	util.RegisterKeyBindings(util.SnippetFormKeys, util.KeyBindings{
+		"next":     &defaultSnippetFormKeyMap.next,
+		"previous": &defaultSnippetFormKeyMap.previous,
+		"submit":   &defaultSnippetFormKeyMap.submit,
+		"close":    &defaultSnippetFormKeyMap.close,
+	})
+}
+
+// SnippetForm asks for a value of every placeholder of the snippet.
+// Enter on the last field submits, the owner then takes the filled snippet
+type SnippetForm struct {
+	snippet      snippets.Snippet
+	placeholders []string
+	inputs       []textinput.Model
+	focused      int
+	keys         snippetFormKeyMap
+	colors       util.SchemeColors
+	width        int
+}
+
+func NewSnippetForm(snippet snippets.Snippet, colors util.SchemeColors) SnippetForm {
+	placeholders := snippets.GetPlaceholders(snippet.Content)
+
+	inputs := []textinput.Model{}
+	for _, placeholder := range placeholders {
+		input := textinput.New()
+		input.Placeholder = placeholder
+		input.Prompt = "> "
+		input.PromptStyle = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
+		inputs = append(inputs, input)
+	}
+
+	form := SnippetForm{
+		snippet:      snippet,
+		placeholders: placeholders,
+		inputs:       inputs,
+		keys:         defaultSnippetFormKeyMap,
+		colors:       colors,
+	}
+	form.focus(0)
+	return form
+}
+
+func (f *SnippetForm) SetWidth(w int) {
+	f.width = w
+	for i := range f.inputs {
+		f.inputs[i].Width = max(w-lipgloss.Width(f.inputs[i].Prompt)-1, 1)
+	}
+}
+
+func (f SnippetForm) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (f SnippetForm) Update(msg tea.Msg) (SnippetForm, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, f.keys.next), key.Matches(msg, f.keys.submit):
+			f.focus(min(f.focused+1, len(f.inputs)-1))
+			return f, nil
+
+		case key.Matches(msg, f.keys.previous):
+			f.focus(max(f.focused-1, 0))
+			return f, nil
+		}
+	}
+
+	if len(f.inputs) == 0 {
+		return f, nil
+	}
+
+	var cmd tea.Cmd
+	f.inputs[f.focused], cmd = f.inputs[f.focused].Update(msg)
+	return f, cmd
+}
+
+func (f *SnippetForm) focus(idx int) {
+	if idx >= len(f.inputs) {
+		return
+	}
+	f.inputs[f.focused].Blur()
+	f.focused = idx
+	f.inputs[f.focused].Focus()
+}
+
+// IsSubmitKey is true for the submit key on the last field, on the other fields it moves on
+func (f SnippetForm) IsSubmitKey(msg tea.KeyMsg) bool {
+	return key.Matches(msg, f.keys.submit) && f.focused == len(f.inputs)-1
+}
+
+func (f SnippetForm) IsCloseKey(msg tea.KeyMsg) bool {
+	return key.Matches(msg, f.keys.close)
+}
+
+func (f SnippetForm) GetName() string {
+	return f.snippet.Name
+}
+
+// GetFilledSnippet returns the content with the typed values, empty fields leave their placeholders in
+func (f SnippetForm) GetFilledSnippet() string {
+	values := map[string]string{}
+	for i, placeholder := range f.placeholders {
+		if value := f.inputs[i].Value(); value != "" {
+			values[placeholder] = value
+		}
+	}
+	return snippets.FillPlaceholders(f.snippet.Content, values)
+}
+
+func (f SnippetForm) HelpKeys() []key.Binding {
+	return []key.Binding{f.keys.submit, f.keys.next, f.keys.previous, f.keys.close}
+}
+
+func (f SnippetForm) View() string {
+	labelStyle := lipgloss.NewStyle().Foreground(f.colors.DefaultTextColor)
+	focusedLabelStyle := labelStyle.Copy().Foreground(f.colors.AccentColor).Bold(true)
+
+	rows := []string{}
+	for i, placeholder := range f.placeholders {
+		style := labelStyle
+		if i == f.focused {
+			style = focusedLabelStyle
+		}
+		rows = append(rows, style.Render(fmt.Sprintf("{{%s}}", placeholder)), f.inputs[i].View(), "")
+	}
+
+	return strings.Join(rows, "\n")
+}
@@ -38,6 +38,19 @@ var defaultKeyMap = keyMap{
 	top:            key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "go to top")),
 }
 
+func init() {
+	util.RegisterKeyBindings(util.SelectionKeys, util.KeyBindings{
+		"visualLineMode": &defaultKeyMap.visualLineMode,
+		"up":             &defaultKeyMap.up,
+		"down":           &defaultKeyMap.down,
+		"pageUp":         &defaultKeyMap.pageUp,
+		"pageDown":       &defaultKeyMap.pageDown,
+		"copy":           &defaultKeyMap.copy,
+		"bottom":         &defaultKeyMap.bottom,
+		"top":            &defaultKeyMap.top,
+	})
+}
+
 type cursor struct {
 	line int
 }
@@ -278,9 +291,24 @@ func (s TextSelector) copySelectedLinesToClipboard() {
 func filterLine(line string) string {
 	line = strings.Replace(line, "🤖", "", -1)
 	line = strings.Replace(line, "💁", "", -1)
+	line = strings.Replace(line, "🔧", "", -1)
+	line = strings.Replace(line, "📎", "", -1)
 	return line
 }
 
+func (s TextSelector) HelpKeys() []key.Binding {
+	return []key.Binding{
+		s.keys.up,
+		s.keys.down,
+		s.keys.pageUp,
+		s.keys.pageDown,
+		s.keys.top,
+		s.keys.bottom,
+		s.keys.visualLineMode,
+		s.keys.copy,
+	}
+}
+
 func (s TextSelector) IsSelecting() bool {
 	return s.Selection.Active
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const configUsage = `Usage:
+  nekot config check [path]    validate the config file and report every invalid field
+  nekot config init [--force]  write a documented config template
+  nekot config path            print the path of the config file`
+
+// runConfigCommand handles `nekot config ...` and returns the exit code
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Println(configUsage)
+		return 2
+	}
+
+	path, err := config.GetDefaultConfigPath()
+	if err != nil {
+		fmt.Println("Failed to get the config path:", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "check":
+		if len(args) > 1 {
+			path = args[1]
+		}
+		return checkConfig(path)
+
+	case "init":
+		flags := flag.NewFlagSet("config init", flag.ContinueOnError)
+		force := flags.Bool("force", false, "Replace an existing config file")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+
+		if err := config.WriteTemplate(path, *force); err != nil {
+			fmt.Println("Failed to write the config:", err)
+			return 1
+		}
+		fmt.Println("Config written to", path)
+		return 0
+
+	case "path":
+		fmt.Println(path)
+		return 0
+	}
+
+	fmt.Println(configUsage)
+	return 2
+}
+
+func checkConfig(path string) int {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s doesn't exist, run `nekot config init` to create it\n", path)
+		return 1
+	}
+
+	// broken themes don't stop the app, so they are only reported as warnings
+	for _, err := range util.LoadThemes() {
+		fmt.Println("Warning:", err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err == nil {
+		for _, conflict := range util.ApplyKeyBindings(cfg.Keys) {
+			fmt.Println("Warning: key conflict:", conflict)
+		}
+		fmt.Printf("%s is valid\n", path)
+		return 0
+	}
+
+	var validationErrs config.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Printf("%s has %d error(s):\n", path, len(validationErrs))
+	for _, validationErr := range validationErrs {
+		fmt.Println("  " + validationErr.Error())
+	}
+	return 1
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/tearingItUp786/nekot/util"
 )
@@ -31,24 +34,161 @@ func FromContext(ctx context.Context) (*Config, bool) {
 }
 
 type Config struct {
-	ChatGPTApiUrl string           `json:"chatGPTAPiUrl"`
-	SystemMessage string           `json:"systemMessage"`
-	DefaultModel  string           `json:"defaultModel"`
-	ColorScheme   util.ColorScheme `json:"colorScheme"`
+	ChatGPTApiUrl string                     `json:"chatGPTAPiUrl"`
+	SystemMessage string                     `json:"systemMessage"`
+	DefaultModel  string                     `json:"defaultModel"`
+	ApiKeyCommand string                     `json:"apiKeyCommand"`
+	ApiKeyStore   string                     `json:"apiKeyStore"`
+	ApiKeyProfile string                     `json:"apiKeyProfile"`
+	ColorScheme   util.ColorScheme           `json:"colorScheme"`
+	Background    util.Background            `json:"background"`
+	Tools         ToolsConfig                `json:"tools"`
+	McpServers    map[string]McpServerConfig `json:"mcpServers"`
+	ContextWindow ContextWindowConfig        `json:"contextWindow"`
+	Pricing       map[string]ModelPricing    `json:"pricing"`
+	Budget        BudgetConfig               `json:"budget"`
+	Retry         RetryConfig                `json:"retry"`
+	Network       NetworkConfig              `json:"network"`
+	Request       RequestConfig              `json:"request"`
+	Azure         AzureConfig                `json:"azure"`
+	Endpoints     EndpointsConfig            `json:"endpoints"`
+	Keys          KeysConfig                 `json:"keys"`
 }
 
-//go:embed config.json
+type ToolsConfig struct {
+	Enabled         bool     `json:"enabled"`
+	AllowedCommands []string `json:"allowedCommands"`
+	AllowedHosts    []string `json:"allowedHosts"`
+	// AllowedRoots limits read_file and list_directory to these directories, empty allows any path
+	AllowedRoots []string `json:"allowedRoots"`
+}
+
+// Limits override built-in context window sizes per model.
+// KeepFirst and KeepLast are used by the `keep_first_last` and `summarize` strategies
+type ContextWindowConfig struct {
+	Limits    map[string]int `json:"limits"`
+	KeepFirst int            `json:"keepFirst"`
+	KeepLast  int            `json:"keepLast"`
+}
+
+// Prices are in USD per million tokens
+type ModelPricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Limits are in USD per calendar month, 0 disables the limit.
+// Soft limit only warns, hard limit blocks new requests
+type BudgetConfig struct {
+	MonthlySoftLimit float64 `json:"monthlySoftLimit"`
+	MonthlyHardLimit float64 `json:"monthlyHardLimit"`
+}
+
+// Failed requests are retried with exponential backoff, zero values fall back to defaults.
+// ResponseTimeoutSec limits waiting for the response headers of a single attempt
+type RetryConfig struct {
+	MaxAttempts        int `json:"maxAttempts"`
+	InitialBackoffMs   int `json:"initialBackoffMs"`
+	MaxBackoffMs       int `json:"maxBackoffMs"`
+	MaxRetryAfterSec   int `json:"maxRetryAfterSec"`
+	ResponseTimeoutSec int `json:"responseTimeoutSec"`
+}
+
+// MaxRetryAttempts bounds retry.maxAttempts, more attempts would keep a failing chat waiting for minutes
+const MaxRetryAttempts = 10
+
+// Proxy falls back to HTTPS_PROXY/HTTP_PROXY env variables when not set.
+// CaBundlePath is a PEM file with certificates trusted in addition to the system ones.
+// ReadTimeoutSec limits how long a response may stay silent, not how long it takes
+type NetworkConfig struct {
+	ProxyUrl           string `json:"proxyUrl"`
+	CaBundlePath       string `json:"caBundlePath"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	ConnectTimeoutSec  int    `json:"connectTimeoutSec"`
+	ReadTimeoutSec     int    `json:"readTimeoutSec"`
+}
+
+const (
+	BearerAuth = "bearer"
+	ApiKeyAuth = "api-key"
+	HeaderAuth = "header"
+	NoAuth     = "none"
+)
+
+// Headers and Query are added to both the models list and completion requests.
+// Values may reference env variables, e.g. `$OPENAI_PROJECT_ID`
+type RequestConfig struct {
+	Headers map[string]string `json:"headers"`
+	Query   map[string]string `json:"query"`
+	Auth    AuthConfig        `json:"auth"`
+}
+
+// Scheme defines how the api key is sent: `bearer`, `api-key`, `header` to send the raw key
+// in the Header, or `none`. Empty scheme picks `api-key` for Azure and `bearer` for the rest
+type AuthConfig struct {
+	Scheme string `json:"scheme"`
+	Header string `json:"header"`
+}
+
+// Deployments map Azure deployment names to the models behind them,
+// the models are used for pricing, context limits and token counting
+type AzureConfig struct {
+	ApiVersion  string            `json:"apiVersion"`
+	Deployments map[string]string `json:"deployments"`
+}
+
+// GetModelName returns the model of a deployment, names that are not deployments are returned as is
+func (a AzureConfig) GetModelName(name string) string {
+	if model, ok := a.Deployments[name]; ok && model != "" {
+		return model
+	}
+	return name
+}
+
+// Full urls that replace the ones derived from ChatGPTApiUrl,
+// `{model}` in ChatCompletions is replaced with the selected model
+type EndpointsConfig struct {
+	ChatCompletions string `json:"chatCompletions"`
+	Models          string `json:"models"`
+}
+
+// KeysConfig overrides key bindings by scope and action, e.g. `"prompt": {"pasteCode": ["ctrl+y"]}`.
+// An empty list disables the action. Bindings are applied on start
+type KeysConfig map[string]map[string][]string
+
+type McpServerConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+}
+
+//go:embed config.json template.jsonc
 var configEmbed embed.FS
 
+// WriteTemplate writes the documented default config. Existing files are only replaced with force
+func WriteTemplate(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to replace it", path)
+	}
+
+	template, err := configEmbed.ReadFile("template.jsonc")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, template, 0644)
+}
+
 func createConfig() (string, error) {
-	appPath, err := util.GetAppDataPath()
+	pathToPersistedFile, err := GetDefaultConfigPath()
 	if err != nil {
 		fmt.Println("Error getting app path:", err)
 		panic(err)
 	}
 
-	pathToPersistedFile := filepath.Join(appPath, "config.json")
-
 	if _, err := os.Stat(pathToPersistedFile); os.IsNotExist(err) {
 		// The database does not exist, extract from embedded
 		configFile, err := configEmbed.Open("config.json")
@@ -81,41 +221,246 @@ func createConfig() (string, error) {
 	return pathToPersistedFile, nil
 }
 
-func validateConfig(config Config) bool {
-	// Validate the ChatAPIURL format (simple example)
-	match, _ := regexp.MatchString(`^https?://`, config.ChatGPTApiUrl)
-	if !match {
-		fmt.Println("ChatAPIURL must be a valid URL")
-		return false
+var urlRegexp = regexp.MustCompile(`^https?://`)
+
+// validateConfig checks the values of a config that matches the schema. Errors only name the field,
+// positions are added by the caller
+func validateConfig(config Config) ValidationErrors {
+	errs := ValidationErrors{}
+	addError := func(field, format string, args ...any) {
+		errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !urlRegexp.MatchString(config.ChatGPTApiUrl) {
+		addError("chatGPTApiUrl", "must be a valid http(s) URL, got %q", config.ChatGPTApiUrl)
+	}
+
+	for name, endpoint := range map[string]string{
+		"endpoints.chatCompletions": config.Endpoints.ChatCompletions,
+		"endpoints.models":          config.Endpoints.Models,
+	} {
+		if endpoint != "" && !urlRegexp.MatchString(endpoint) {
+			addError(name, "must be a valid http(s) URL, got %q", endpoint)
+		}
+	}
+
+	if config.ColorScheme != "" && !util.IsColorSchemeAvailable(config.ColorScheme) {
+		addError("colorScheme", "unknown color scheme %q, expected one of: %s", config.ColorScheme, joinValues(util.GetColorSchemes()))
+	}
+
+	switch config.Background {
+	case "", util.AutoBackground, util.DarkBackground, util.LightBackground:
+	default:
+		addError("background", "unknown background %q, expected auto, dark or light", config.Background)
+	}
+
+	if config.ApiKeyStore != "" && config.ApiKeyStore != "keyring" && config.ApiKeyStore != "file" {
+		addError("apiKeyStore", "unknown store %q, expected keyring or file", config.ApiKeyStore)
+	}
+
+	switch config.Request.Auth.Scheme {
+	case "", BearerAuth, ApiKeyAuth, NoAuth:
+	case HeaderAuth:
+		if config.Request.Auth.Header == "" {
+			addError("request.auth.header", "must be set for the `header` auth scheme")
+		}
+	default:
+		addError("request.auth.scheme", "unknown auth scheme %q, expected one of: bearer, api-key, header, none", config.Request.Auth.Scheme)
+	}
+
+	for scope, actions := range config.Keys {
+		knownActions, ok := util.GetKeyActions(util.KeyScope(scope))
+		if !ok {
+			addError("keys."+scope, "unknown scope, expected one of: %s", joinValues(util.GetKeyScopes()))
+			continue
+		}
+		for action, keys := range actions {
+			if !slices.Contains(knownActions, action) {
+				addError("keys."+scope+"."+action, "unknown action, expected one of: %s", strings.Join(knownActions, ", "))
+			}
+			if slices.Contains(keys, "") {
+				addError("keys."+scope+"."+action, "keys must not be empty")
+			}
+		}
+	}
+
+	for i, root := range config.Tools.AllowedRoots {
+		if !filepath.IsAbs(root) {
+			addError(fmt.Sprintf("tools.allowedRoots[%d]", i), "must be an absolute path, got %q", root)
+		}
 	}
-	// Add any other validation logic here
-	return true
+
+	for name, server := range config.McpServers {
+		if server.Command == "" {
+			addError("mcpServers."+name+".command", "must be set")
+		}
+	}
+
+	// zero means the default everywhere, negative values are always a mistake
+	numbers := map[string]float64{
+		"contextWindow.keepFirst":   float64(config.ContextWindow.KeepFirst),
+		"contextWindow.keepLast":    float64(config.ContextWindow.KeepLast),
+		"budget.monthlySoftLimit":   config.Budget.MonthlySoftLimit,
+		"budget.monthlyHardLimit":   config.Budget.MonthlyHardLimit,
+		"retry.maxAttempts":         float64(config.Retry.MaxAttempts),
+		"retry.initialBackoffMs":    float64(config.Retry.InitialBackoffMs),
+		"retry.maxBackoffMs":        float64(config.Retry.MaxBackoffMs),
+		"retry.maxRetryAfterSec":    float64(config.Retry.MaxRetryAfterSec),
+		"retry.responseTimeoutSec":  float64(config.Retry.ResponseTimeoutSec),
+		"network.connectTimeoutSec": float64(config.Network.ConnectTimeoutSec),
+		"network.readTimeoutSec":    float64(config.Network.ReadTimeoutSec),
+	}
+	for model, limit := range config.ContextWindow.Limits {
+		numbers["contextWindow.limits."+model] = float64(limit)
+	}
+	for model, pricing := range config.Pricing {
+		numbers["pricing."+model+".input"] = pricing.Input
+		numbers["pricing."+model+".output"] = pricing.Output
+	}
+	for name, value := range numbers {
+		if value < 0 {
+			addError(name, "must not be negative, got %v", value)
+		}
+	}
+	if config.Retry.MaxAttempts > MaxRetryAttempts {
+		addError("retry.maxAttempts", "must be at most %d, got %d", MaxRetryAttempts, config.Retry.MaxAttempts)
+	}
+
+	// maps are iterated in random order, the report should be stable
+	slices.SortFunc(errs, func(a, b ValidationError) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return errs
 }
 
-func CreateAndValidateConfig() Config {
-	configFilePath, err := createConfig()
+func joinValues[T ~string](values []T) string {
+	result := []string{}
+	for _, value := range values {
+		result = append(result, string(value))
+	}
+	return strings.Join(result, ", ")
+}
+
+var colorSchemeRegexp = regexp.MustCompile(`(?i)("colorScheme"\s*:\s*)"[^"]*"`)
+
+// SaveColorScheme updates only the colorScheme of the config file, so that comments and formatting are kept
+func SaveColorScheme(path string, scheme util.ColorScheme) error {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Error finding config JSON: %s", err)
-		panic(err)
+		return err
 	}
 
-	content, err := os.ReadFile(configFilePath)
+	value, err := json.Marshal(scheme)
 	if err != nil {
-		fmt.Printf("Error reading config JSON: %s", err)
-		panic(err)
+		return err
 	}
 
+	var updated []byte
+	if match := colorSchemeRegexp.FindSubmatchIndex(content); match != nil {
+		updated = slices.Concat(content[:match[3]], value, content[match[1]:])
+	} else {
+		start := bytes.IndexByte(content, '{')
+		if start < 0 {
+			return fmt.Errorf("%s is not a JSON object", path)
+		}
+		field := []byte("\n  \"colorScheme\": " + string(value))
+		if !bytes.HasPrefix(bytes.TrimSpace(content[start+1:]), []byte("}")) {
+			field = append(field, ',')
+		}
+		updated = slices.Concat(content[:start+1], field, content[start+1:])
+	}
+
+	// the file is only replaced if it stays valid
+	if _, err := ParseConfig(updated); err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, 0644)
+}
+
+// GetDefaultConfigPath returns where the config file is expected, without creating it
+func GetDefaultConfigPath() (string, error) {
+	appPath, err := util.GetAppConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appPath, "config.json"), nil
+}
+
+// GetConfigPath returns the path of the config file, creating it from the defaults on the first run
+func GetConfigPath() (string, error) {
+	return createConfig()
+}
+
+// LoadConfig reads and validates the config file without panicking, so that it can be reloaded at runtime.
+// Invalid configs return ValidationErrors with the positions of the offending fields
+func LoadConfig(path string) (Config, error) {
 	var config Config
 
-	err = json.Unmarshal(content, &config)
+	content, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Error parsing config JSON: %s", err)
+		return config, fmt.Errorf("Error reading config JSON: %w", err)
+	}
+
+	return ParseConfig(content)
+}
+
+// ParseConfig parses the content of a config file, which may contain comments.
+// Schema and value errors are reported together, unless the file is not valid JSON at all
+func ParseConfig(content []byte) (Config, error) {
+	var config Config
+
+	content = stripComments(content)
+	errs, offsets := checkSchema(content)
+	if slices.ContainsFunc(errs, func(err ValidationError) bool { return err.Field == "" }) {
+		return config, errs
+	}
+
+	// fields of the wrong type are already reported, the rest is still decoded and checked
+	if err := json.Unmarshal(content, &config); err != nil && len(errs) == 0 {
+		return config, fmt.Errorf("Error parsing config JSON: %w", err)
+	}
+
+	for _, err := range validateConfig(config) {
+		if slices.ContainsFunc(errs, func(e ValidationError) bool { return strings.EqualFold(e.Field, err.Field) }) {
+			continue
+		}
+
+		// errors of map entries point to the entry, when the field itself is missing
+		path := strings.ToLower(err.Field)
+		for path != "" {
+			if offset, ok := offsets[path]; ok {
+				err.Line, err.Column = getPosition(content, offset)
+				break
+			}
+			path = path[:max(strings.LastIndex(path, "."), 0)]
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return config, nil
+	}
+
+	slices.SortStableFunc(errs, func(a, b ValidationError) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return config, errs
+}
+
+func CreateAndValidateConfig() Config {
+	configFilePath, err := createConfig()
+	if err != nil {
+		fmt.Printf("Error finding config JSON: %s", err)
 		panic(err)
 	}
 
-	isValidConfig := validateConfig(config)
-	if !isValidConfig {
-		panic(fmt.Errorf("Invalid config"))
+	config, err := LoadConfig(configFilePath)
+	if err != nil {
+		fmt.Printf("Invalid config %s:\n%s\n", configFilePath, err)
+		os.Exit(1)
 	}
 
 	return config
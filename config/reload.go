@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+// ConfigChanged is sent when the config file was edited and the new version is valid
+type ConfigChanged struct {
+	Config Config
+}
+
+// ConfigReloadFailed is sent when the edited config is invalid, the previous config stays in use
+type ConfigReloadFailed struct {
+	Err error
+}
+
+// ConfigFileChecked carries the modification time of the config file, zero if it couldn't be read
+type ConfigFileChecked struct {
+	ModTime time.Time
+}
+
+func GetConfigModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// CheckConfigFile polls the config file, editors replace files in too many ways for a reliable watcher
+func CheckConfigFile(path string, interval time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		time.Sleep(interval)
+		return ConfigFileChecked{ModTime: GetConfigModTime(path)}
+	}
+}
+
+// ReloadConfig reloads the user themes as well, so that edited themes are applied together with the config
+func ReloadConfig(path string) tea.Cmd {
+	return func() tea.Msg {
+		util.LoadThemes()
+		config, err := LoadConfig(path)
+		if err != nil {
+			return ConfigReloadFailed{Err: err}
+		}
+		util.SetBackground(config.Background)
+		return ConfigChanged{Config: config}
+	}
+}
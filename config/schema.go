@@ -0,0 +1,350 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ValidationError points to the field of the config file that is invalid.
+// Line and Column are 1-based, zero when the position is unknown
+type ValidationError struct {
+	Field   string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	message := e.Message
+	if e.Field != "" {
+		message = e.Field + ": " + message
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, message)
+	}
+	return message
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// schemaWalker checks the config file against the Config struct token by token,
+// so that every unknown key and wrong type is reported with its position instead of only the first one
+type schemaWalker struct {
+	content []byte
+	decoder *json.Decoder
+	errors  ValidationErrors
+	// lowercased field paths to the offsets of their keys, used to place semantic errors
+	offsets map[string]int
+}
+
+// checkSchema returns the syntax and schema errors of the file and offsets of all fields it contains
+func checkSchema(content []byte) (ValidationErrors, map[string]int) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	w := &schemaWalker{
+		content: content,
+		decoder: decoder,
+		offsets: map[string]int{},
+	}
+
+	err := w.walk("", reflect.TypeOf(Config{}))
+	if err == nil {
+		if _, err = decoder.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errors.New("unexpected content after the end of the config")
+		}
+	}
+
+	if err != nil {
+		w.errors = append(w.errors, w.syntaxError(err))
+	}
+
+	return w.errors, w.offsets
+}
+
+// walk consumes a single value. Only decoder errors are returned, schema errors are collected
+func (w *schemaWalker) walk(path string, t reflect.Type) error {
+	offset := w.nextTokenOffset()
+	token, err := w.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	// null leaves the defaults in place, same as json.Unmarshal does
+	if token == nil {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if token != json.Delim('{') {
+			return w.mismatch(path, offset, token, "an object")
+		}
+		for w.decoder.More() {
+			keyOffset := w.nextTokenOffset()
+			key, err := w.readKey()
+			if err != nil {
+				return err
+			}
+
+			fieldPath := joinPath(path, key)
+			field, ok := findField(t, key)
+			if !ok {
+				w.addError(fieldPath, keyOffset, "unknown field")
+				if err := w.skipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			w.offsets[strings.ToLower(fieldPath)] = keyOffset
+			if err := w.walk(fieldPath, field.Type); err != nil {
+				return err
+			}
+		}
+		_, err = w.decoder.Token()
+		return err
+
+	case reflect.Map:
+		if token != json.Delim('{') {
+			return w.mismatch(path, offset, token, "an object")
+		}
+		for w.decoder.More() {
+			keyOffset := w.nextTokenOffset()
+			key, err := w.readKey()
+			if err != nil {
+				return err
+			}
+
+			fieldPath := joinPath(path, key)
+			w.offsets[strings.ToLower(fieldPath)] = keyOffset
+			if err := w.walk(fieldPath, t.Elem()); err != nil {
+				return err
+			}
+		}
+		_, err = w.decoder.Token()
+		return err
+
+	case reflect.Slice:
+		if token != json.Delim('[') {
+			return w.mismatch(path, offset, token, "an array")
+		}
+		for i := 0; w.decoder.More(); i++ {
+			if err := w.walk(fmt.Sprintf("%s[%d]", path, i), t.Elem()); err != nil {
+				return err
+			}
+		}
+		_, err = w.decoder.Token()
+		return err
+
+	case reflect.String:
+		if _, ok := token.(string); !ok {
+			return w.mismatch(path, offset, token, "a string")
+		}
+
+	case reflect.Bool:
+		if _, ok := token.(bool); !ok {
+			return w.mismatch(path, offset, token, "true or false")
+		}
+
+	case reflect.Int:
+		number, ok := token.(json.Number)
+		if !ok {
+			return w.mismatch(path, offset, token, "a whole number")
+		}
+		if _, err := number.Int64(); err != nil {
+			w.addError(path, offset, fmt.Sprintf("expected a whole number, got %s", number))
+		}
+
+	case reflect.Float64:
+		if _, ok := token.(json.Number); !ok {
+			return w.mismatch(path, offset, token, "a number")
+		}
+	}
+
+	return nil
+}
+
+func (w *schemaWalker) readKey() (string, error) {
+	token, err := w.decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	key, _ := token.(string)
+	return key, nil
+}
+
+// mismatch reports a value of the wrong type and skips the rest of it, if it is an object or array
+func (w *schemaWalker) mismatch(path string, offset int, token json.Token, expected string) error {
+	w.addError(path, offset, fmt.Sprintf("expected %s, got %s", expected, describeToken(token)))
+
+	if token == json.Delim('{') || token == json.Delim('[') {
+		return w.skipNested()
+	}
+	return nil
+}
+
+func (w *schemaWalker) skipValue() error {
+	token, err := w.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == json.Delim('{') || token == json.Delim('[') {
+		return w.skipNested()
+	}
+	return nil
+}
+
+// skipNested consumes tokens until the already opened object or array is closed
+func (w *schemaWalker) skipNested() error {
+	for depth := 1; depth > 0; {
+		token, err := w.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// nextTokenOffset skips separators after the decoder position, so that errors point to the token itself
+func (w *schemaWalker) nextTokenOffset() int {
+	offset := int(w.decoder.InputOffset())
+	for offset < len(w.content) && strings.IndexByte(" \t\r\n,:", w.content[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func (w *schemaWalker) addError(path string, offset int, message string) {
+	line, column := getPosition(w.content, offset)
+	w.errors = append(w.errors, ValidationError{
+		Field:   path,
+		Line:    line,
+		Column:  column,
+		Message: message,
+	})
+}
+
+func (w *schemaWalker) syntaxError(err error) ValidationError {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := getPosition(w.content, int(syntaxErr.Offset))
+		return ValidationError{Line: line, Column: column, Message: "invalid JSON: " + syntaxErr.Error()}
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		line, column := getPosition(w.content, len(w.content))
+		return ValidationError{Line: line, Column: column, Message: "invalid JSON: unexpected end of the file"}
+	}
+
+	return ValidationError{Message: "invalid JSON: " + err.Error()}
+}
+
+// findField matches keys case-insensitively, same as json.Unmarshal
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func describeToken(token json.Token) string {
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' {
+			return "an object"
+		}
+		return "an array"
+	case string:
+		return fmt.Sprintf("string %q", value)
+	case json.Number:
+		return "number " + value.String()
+	case bool:
+		return fmt.Sprintf("%t", value)
+	}
+	return fmt.Sprintf("%v", token)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func getPosition(content []byte, offset int) (int, int) {
+	offset = min(max(offset, 0), len(content))
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// stripComments blanks out `//` and `/* */` comments outside of strings, so that the config
+// may be documented. Comments are replaced with spaces to keep the positions of errors intact
+func stripComments(content []byte) []byte {
+	result := bytes.Clone(content)
+	inString := false
+
+	for i := 0; i < len(result); i++ {
+		switch {
+		case inString:
+			if result[i] == '\\' {
+				i++
+			} else if result[i] == '"' {
+				inString = false
+			}
+
+		case result[i] == '"':
+			inString = true
+
+		case result[i] == '/' && i+1 < len(result) && result[i+1] == '/':
+			for ; i < len(result) && result[i] != '\n'; i++ {
+				result[i] = ' '
+			}
+
+		case result[i] == '/' && i+1 < len(result) && result[i+1] == '*':
+			end := bytes.Index(result[i+2:], []byte("*/"))
+			// unterminated comments are left to the JSON parser to report
+			if end < 0 {
+				return result
+			}
+			for j := i; j < i+2+end+2; j++ {
+				if result[j] != '\n' {
+					result[j] = ' '
+				}
+			}
+			i += 2 + end + 1
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,46 @@
+package history
+
+import (
+	"database/sql"
+)
+
+// HistoryService keeps the sent prompts of all sessions
+type HistoryService struct {
+	DB *sql.DB
+}
+
+func NewHistoryService(db *sql.DB) *HistoryService {
+	return &HistoryService{
+		DB: db,
+	}
+}
+
+func (hs *HistoryService) AddPrompt(prompt string) error {
+	_, err := hs.DB.Exec(`INSERT INTO prompt_history (history_prompt) VALUES ($1)`, prompt)
+	return err
+}
+
+// GetPrompts returns the newest prompts first, a prompt sent several times is listed once
+func (hs *HistoryService) GetPrompts(limit int) ([]string, error) {
+	rows, err := hs.DB.Query(`
+		SELECT history_prompt FROM prompt_history
+		GROUP BY history_prompt
+		ORDER BY MAX(history_id) DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prompts := []string{}
+	for rows.Next() {
+		var prompt string
+		if err := rows.Scan(&prompt); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	return prompts, rows.Err()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -13,19 +14,27 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/tearingItUp786/nekot/config"
 	"github.com/tearingItUp786/nekot/migrations"
+	"github.com/tearingItUp786/nekot/secrets"
+	"github.com/tearingItUp786/nekot/usage"
 	"github.com/tearingItUp786/nekot/util"
 	"github.com/tearingItUp786/nekot/views"
 )
 
 var purgeCache bool
+var usageReport bool
 
 func init() {
 	flag.BoolVar(&purgeCache, "purge-cache", false, "Invalidate models cache")
+	flag.BoolVar(&usageReport, "usage-report", false, "Print token usage and cost per day, model and session")
 }
 
 func main() {
 	flag.Parse()
 
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(flag.Args()[1:]))
+	}
+
 	env := os.Getenv("FOO_ENV")
 	if "" == env {
 		env = "development"
@@ -46,18 +55,27 @@ func main() {
 	}
 	defer f.Close()
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if "" == apiKey {
-		fmt.Println("OPENAI_API_KEY not set; set it in your profile")
-		fmt.Printf("export OPENAI_API_KEY=your_key in the config for :%v \n", os.Getenv("SHELL"))
-		fmt.Println("Exiting...")
-		os.Exit(1)
-	}
-
 	// delete files if in dev mode
 	util.DeleteFilesIfDevMode()
+	// invalid themes fall back to the default colors, errors go to the debug log
+	util.LoadThemes()
 	// validate config
 	configToUse := config.CreateAndValidateConfig()
+	util.DetectBackground()
+	// conflicts are shown in the info pane
+	util.ApplyKeyBindings(configToUse.Keys)
+	util.SetBackground(configToUse.Background)
+
+	isKeyRequired := !usageReport && configToUse.Request.Auth.Scheme != config.NoAuth
+	if isKeyRequired {
+		apiKey, err := resolveApiKey(configToUse)
+		if err != nil {
+			fmt.Println("Failed to get the api key:", err)
+			fmt.Println("Exiting...")
+			os.Exit(1)
+		}
+		secrets.SetApiKey(apiKey)
+	}
 
 	// run migrations for our database
 	db := util.InitDb()
@@ -77,6 +95,14 @@ func main() {
 		}
 	}
 
+	if usageReport {
+		err = usage.NewUsageService(db).WriteReport(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	ctxWithConfig := config.WithConfig(ctx, &configToUse)
 
@@ -90,3 +116,42 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// resolveApiKey asks for the passphrase of the keys file when needed,
+// and for the key itself on the first run, instead of requiring the env variable
+func resolveApiKey(cfg config.Config) (string, error) {
+	passphrase := secrets.GetPassphraseFromEnv()
+	apiKey, err := secrets.ResolveApiKey(cfg, passphrase)
+
+	if errors.Is(err, secrets.ErrPassphraseRequired) {
+		prompt, err := views.RunApiKeyPrompt(cfg, false, true)
+		if err != nil {
+			return "", err
+		}
+		if prompt.Cancelled {
+			return "", secrets.ErrPassphraseRequired
+		}
+		return secrets.ResolveApiKey(cfg, prompt.Passphrase)
+	}
+
+	if !errors.Is(err, secrets.ErrNoApiKey) {
+		return apiKey, err
+	}
+
+	prompt, err := views.RunApiKeyPrompt(cfg, true, secrets.IsPassphraseRequired(cfg))
+	if err != nil {
+		return "", err
+	}
+	if prompt.Cancelled {
+		return "", fmt.Errorf("set OPENAI_API_KEY, apiKeyCommand or enter the key on start")
+	}
+	if passphrase == "" {
+		passphrase = prompt.Passphrase
+	}
+
+	// the key still works for this run, even if it couldn't be saved
+	if err := secrets.SaveApiKey(cfg, prompt.ApiKey, passphrase); err != nil {
+		log.Println("Failed to save the api key:", err)
+	}
+	return prompt.ApiKey, nil
+}
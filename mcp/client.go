@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const requestTimeout = time.Second * 60
+
+var ErrClientClosed = errors.New("mcp server connection is closed")
+
+// Client talks to a single MCP server over stdio using newline delimited JSON-RPC messages
+type Client struct {
+	Name string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	writeM sync.Mutex
+	// readers of stdout and stderr, the pipes must not be read once cmd.Wait is called
+	readers sync.WaitGroup
+
+	pendingM sync.Mutex
+	pending  map[int]chan rpcResponse
+	nextID   int
+	closed   bool
+}
+
+func StartClient(name string, command string, args []string, env map[string]string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mcp server %s: %w", name, err)
+	}
+
+	client := &Client{
+		Name:    name,
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+		pending: map[int]chan rpcResponse{},
+	}
+
+	client.readers.Add(2)
+	go client.readLoop()
+	go client.logStderr()
+
+	if err := client.initialize(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to initialize mcp server %s: %w", name, err)
+	}
+
+	return client, nil
+}
+
+func (c *Client) initialize() error {
+	params := map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "nekot",
+			"version": "1.0.0",
+		},
+	}
+
+	if _, err := c.call("initialize", params); err != nil {
+		return err
+	}
+
+	return c.notify("notifications/initialized")
+}
+
+func (c *Client) ListTools() ([]ToolDescription, error) {
+	tools := []ToolDescription{}
+	cursor := ""
+
+	for {
+		var result listToolsResult
+		if err := c.callAndDecode("tools/list", cursorParams(cursor), &result); err != nil {
+			return nil, err
+		}
+
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func (c *Client) ListResources() ([]ResourceDescription, error) {
+	resources := []ResourceDescription{}
+	cursor := ""
+
+	for {
+		var result listResourcesResult
+		if err := c.callAndDecode("resources/list", cursorParams(cursor), &result); err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, result.Resources...)
+		if result.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func (c *Client) CallTool(name string, args map[string]interface{}) (string, error) {
+	params := map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	}
+
+	var result callToolResult
+	if err := c.callAndDecode("tools/call", params, &result); err != nil {
+		return "", err
+	}
+
+	output := joinContent(result.Content)
+	if result.IsError {
+		return "", errors.New(output)
+	}
+
+	return output, nil
+}
+
+func (c *Client) ReadResource(uri string) (string, error) {
+	params := map[string]interface{}{
+		"uri": uri,
+	}
+
+	var result readResourceResult
+	if err := c.callAndDecode("resources/read", params, &result); err != nil {
+		return "", err
+	}
+
+	return joinContent(result.Contents), nil
+}
+
+func (c *Client) Close() {
+	c.pendingM.Lock()
+	c.closed = true
+	c.pendingM.Unlock()
+
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	// children of the server, e.g. the ones started by npx, may keep the pipes open after the kill
+	c.stdout.Close()
+	c.stderr.Close()
+	c.readers.Wait()
+	c.cmd.Wait()
+}
+
+func (c *Client) callAndDecode(method string, params interface{}, target interface{}) error {
+	result, err := c.call(method, params)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(result, target)
+}
+
+func (c *Client) call(method string, params interface{}) (json.RawMessage, error) {
+	c.pendingM.Lock()
+	if c.closed {
+		c.pendingM.Unlock()
+		return nil, ErrClientClosed
+	}
+	c.nextID++
+	id := c.nextID
+	responseChan := make(chan rpcResponse, 1)
+	c.pending[id] = responseChan
+	c.pendingM.Unlock()
+
+	defer func() {
+		c.pendingM.Lock()
+		delete(c.pending, id)
+		c.pendingM.Unlock()
+	}()
+
+	err := c.write(rpcRequest{JsonRpc: jsonRpcVersion, ID: &id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case response, ok := <-responseChan:
+		if !ok {
+			return nil, ErrClientClosed
+		}
+		if response.Error != nil {
+			return nil, fmt.Errorf("%s failed: %s (code %d)", method, response.Error.Message, response.Error.Code)
+		}
+		return response.Result, nil
+
+	case <-time.After(requestTimeout):
+		return nil, fmt.Errorf("%s timed out", method)
+	}
+}
+
+func (c *Client) notify(method string) error {
+	return c.write(rpcRequest{JsonRpc: jsonRpcVersion, Method: method})
+}
+
+func (c *Client) write(request rpcRequest) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	c.writeM.Lock()
+	defer c.writeM.Unlock()
+	_, err = c.stdin.Write(append(payload, '\n'))
+	return err
+}
+
+// logStderr keeps the output of the server, it usually tells why the server failed to start
+func (c *Client) logStderr() {
+	defer c.readers.Done()
+
+	scanner := bufio.NewScanner(c.stderr)
+	for scanner.Scan() {
+		util.Log("mcp:", c.Name, "stderr:", scanner.Text())
+	}
+	// a line too long for the scanner stops it, the server must not block on a full pipe
+	io.Copy(io.Discard, c.stderr)
+}
+
+func (c *Client) readLoop() {
+	defer c.readers.Done()
+
+	scanner := bufio.NewScanner(c.stdout)
+	// tool results may contain whole files
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var response rpcResponse
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			util.Log("mcp: failed to parse message from", c.Name, err)
+			continue
+		}
+
+		// server side requests and notifications are not supported, skip them
+		if response.ID == nil {
+			continue
+		}
+
+		c.pendingM.Lock()
+		responseChan, ok := c.pending[*response.ID]
+		c.pendingM.Unlock()
+		if ok {
+			responseChan <- response
+		}
+	}
+
+	util.Log("mcp: server stopped", c.Name, scanner.Err())
+
+	c.pendingM.Lock()
+	c.closed = true
+	for id, responseChan := range c.pending {
+		close(responseChan)
+		delete(c.pending, id)
+	}
+	c.pendingM.Unlock()
+}
+
+func cursorParams(cursor string) interface{} {
+	if cursor == "" {
+		return nil
+	}
+	return map[string]interface{}{"cursor": cursor}
+}
+
+func joinContent(items []contentItem) string {
+	parts := []string{}
+	for _, item := range items {
+		switch {
+		case item.Text != "":
+			parts = append(parts, item.Text)
+		case item.Type != "" && item.Type != "text":
+			parts = append(parts, fmt.Sprintf("[%s content omitted]", item.Type))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import "encoding/json"
+
+const (
+	jsonRpcVersion  = "2.0"
+	protocolVersion = "2024-11-05"
+)
+
+type rpcRequest struct {
+	JsonRpc string      `json:"jsonrpc"`
+	ID      *int        `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	ID      *int            `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type ToolDescription struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+type listToolsResult struct {
+	Tools      []ToolDescription `json:"tools"`
+	NextCursor string            `json:"nextCursor"`
+}
+
+type ResourceDescription struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MimeType    string `json:"mimeType"`
+}
+
+type listResourcesResult struct {
+	Resources  []ResourceDescription `json:"resources"`
+	NextCursor string                `json:"nextCursor"`
+}
+
+type contentItem struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	URI  string `json:"uri"`
+}
+
+type callToolResult struct {
+	Content []contentItem `json:"content"`
+	IsError bool          `json:"isError"`
+}
+
+type readResourceResult struct {
+	Contents []contentItem `json:"contents"`
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE settings ADD COLUMN settings_reasoning_effort VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE settings DROP COLUMN settings_reasoning_effort;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN context_strategy VARCHAR(255) NOT NULL DEFAULT 'drop_oldest';
+ALTER TABLE sessions ADD COLUMN context_summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN context_summarized_count INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN context_strategy;
+ALTER TABLE sessions DROP COLUMN context_summary;
+ALTER TABLE sessions DROP COLUMN context_summarized_count;
+-- +goose StatementEnd
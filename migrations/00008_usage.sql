@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE usage (
+  usage_id INTEGER PRIMARY KEY,
+  usage_session_id INTEGER NOT NULL,
+  usage_model VARCHAR(255) NOT NULL,
+  usage_prompt_tokens INTEGER NOT NULL DEFAULT 0,
+  usage_completion_tokens INTEGER NOT NULL DEFAULT 0,
+  usage_cost REAL NOT NULL DEFAULT 0,
+  usage_created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX usage_created_at_idx ON usage (usage_created_at);
+CREATE INDEX usage_session_id_idx ON usage (usage_session_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE usage;
+-- +goose StatementEnd
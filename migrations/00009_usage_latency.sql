@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE usage ADD COLUMN usage_latency_ms INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE usage DROP COLUMN usage_latency_ms;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN system_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN settings_temperature REAL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN system_message;
+ALTER TABLE settings DROP COLUMN settings_temperature;
+-- +goose StatementEnd
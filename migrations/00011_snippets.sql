@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE snippets (
+  snippets_id INTEGER PRIMARY KEY,
+  snippets_name VARCHAR(255) NOT NULL UNIQUE,
+  snippets_content TEXT NOT NULL,
+  snippets_created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE snippets;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE prompt_history (
+  history_id INTEGER PRIMARY KEY,
+  history_prompt TEXT NOT NULL,
+  history_created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE prompt_history;
+-- +goose StatementEnd
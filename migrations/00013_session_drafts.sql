@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN draft TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN draft_in_editor INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN draft;
+ALTER TABLE sessions DROP COLUMN draft_in_editor;
+-- +goose StatementEnd
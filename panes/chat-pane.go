@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -22,6 +23,35 @@ const (
 	selectionMode
 )
 
+type chatKeyMap struct {
+	selectionMode key.Binding
+	reasoning     key.Binding
+	copyLast      key.Binding
+	copyAll       key.Binding
+	exitSelection key.Binding
+}
+
+var defaultChatKeyMap = chatKeyMap{
+	selectionMode: key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "enter navigation mode")),
+	reasoning:     key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "expand/collapse reasoning")),
+	copyLast:      key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy last message")),
+	copyAll:       key.NewBinding(key.WithKeys("Y"), key.WithHelp("Y", "copy all messages")),
+	exitSelection: key.NewBinding(key.WithKeys(tea.KeyEsc.String()), key.WithHelp("esc", "quit navigation mode")),
+}
+
+func init() {
+	util.RegisterKeyBindings(util.ChatKeys, util.KeyBindings{
+		"selectionMode": &defaultChatKeyMap.selectionMode,
+		"reasoning":     &defaultChatKeyMap.reasoning,
+		"copyLast":      &defaultChatKeyMap.copyLast,
+		"copyAll":       &defaultChatKeyMap.copyAll,
+	})
+	// navigation mode is left by the chat pane, the selector only handles the movements
+	util.RegisterKeyBindings(util.SelectionKeys, util.KeyBindings{
+		"exit": &defaultChatKeyMap.exitSelection,
+	})
+}
+
 type ChatPane struct {
 	isChatPaneReady        bool
 	chatViewReady          bool
@@ -31,6 +61,9 @@ type ChatPane struct {
 	isChatContainerFocused bool
 	msgChan                chan clients.ProcessApiCompletionResponse
 	viewMode               util.ViewMode
+	showReasoning          bool
+	currentMessages        []util.MessageToSend
+	keys                   chatKeyMap
 
 	terminalWidth  int
 	terminalHeight int
@@ -55,17 +88,15 @@ func NewChatPane(ctx context.Context, w, h int) ChatPane {
 		fmt.Println("No config found")
 		panic("No config found in context")
 	}
-	colors := config.ColorScheme.GetColors()
 
 	chatContainerStyle = chatContainerStyle.
 		Copy().
 		Width(w).
-		Height(h).
-		BorderForeground(colors.NormalTabBorderColor)
+		Height(h)
 
-	return ChatPane{
+	pane := ChatPane{
 		viewMode:               util.NormalMode,
-		colors:                 colors,
+		keys:                   defaultChatKeyMap,
 		chatContainer:          chatContainerStyle,
 		chatView:               chatView,
 		chatViewReady:          false,
@@ -77,6 +108,21 @@ func NewChatPane(ctx context.Context, w, h int) ChatPane {
 		terminalHeight:         util.DefaultTerminalHeight,
 		displayMode:            normalMode,
 	}
+	pane.applyColors(config.ColorScheme.GetColors())
+	return pane
+}
+
+func (p *ChatPane) applyColors(colors util.SchemeColors) {
+	p.colors = colors
+
+	borderColor := colors.NormalTabBorderColor
+	switch {
+	case p.IsSelectionMode():
+		borderColor = colors.AccentColor
+	case p.isChatContainerFocused:
+		borderColor = colors.ActiveTabBorderColor
+	}
+	p.chatContainer = p.chatContainer.Copy().BorderForeground(borderColor)
 }
 
 func waitForActivity(sub chan clients.ProcessApiCompletionResponse) tea.Cmd {
@@ -123,6 +169,12 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 		}
 		return p, nil
 
+	case config.ConfigChanged:
+		p.applyColors(msg.Config.ColorScheme.GetColors())
+		if p.isChatPaneReady {
+			p.renderMessages(p.currentMessages)
+		}
+
 	case sessions.LoadDataFromDB:
 		return p.initializePane(msg.Session)
 
@@ -131,9 +183,18 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 
 	case sessions.ResponseChunkProcessed:
 		paneWidth := p.chatContainer.GetWidth()
+		p.currentMessages = msg.PreviousMsgArray
 
-		oldContent := util.GetMessagesAsPrettyString(msg.PreviousMsgArray, paneWidth, p.colors)
-		styledBufferMessage := util.RenderBotMessage(msg.ChunkMessage, paneWidth, p.colors, false)
+		reasoning, answer := util.SplitThinkingBlock(msg.ChunkMessage)
+		reasoning = msg.ChunkReasoning + reasoning
+
+		oldContent := util.GetMessagesAsPrettyString(msg.PreviousMsgArray, paneWidth, p.colors, p.showReasoning)
+		styledBufferMessage := util.RenderBotMessage(answer, paneWidth, p.colors, false)
+
+		styledReasoning := util.RenderReasoning(reasoning, paneWidth, p.colors, p.showReasoning)
+		if styledReasoning != "" {
+			styledBufferMessage = styledReasoning + "\n" + styledBufferMessage
+		}
 
 		if styledBufferMessage != "" {
 			styledBufferMessage = "\n" + styledBufferMessage
@@ -162,8 +223,7 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 		}
 
 		if p.IsSelectionMode() {
-			switch msg.Type {
-			case tea.KeyEsc:
+			if key.Matches(msg, p.keys.exitSelection) {
 				p.displayMode = normalMode
 				p.chatContainer.BorderForeground(p.colors.ActiveTabBorderColor)
 			}
@@ -173,8 +233,8 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 			break
 		}
 
-		switch keypress := msg.String(); keypress {
-		case "v":
+		switch {
+		case key.Matches(msg, p.keys.selectionMode):
 			if !p.isChatContainerFocused {
 				break
 			}
@@ -189,7 +249,13 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 				p.colors)
 			p.selectionView.AdjustScroll()
 
-		case "y":
+		case key.Matches(msg, p.keys.reasoning):
+			if p.isChatContainerFocused {
+				p.showReasoning = !p.showReasoning
+				p.renderMessages(p.currentMessages)
+			}
+
+		case key.Matches(msg, p.keys.copyLast):
 			if p.isChatContainerFocused {
 				copyLast := func() tea.Msg {
 					return util.SendCopyLastMsg()
@@ -197,7 +263,7 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 				cmds = append(cmds, copyLast)
 			}
 
-		case "Y":
+		case key.Matches(msg, p.keys.copyAll):
 			if p.isChatContainerFocused {
 				copyAll := func() tea.Msg {
 					return util.SendCopyAllMsgs()
@@ -215,6 +281,22 @@ func (p ChatPane) Update(msg tea.Msg) (ChatPane, tea.Cmd) {
 	return p, tea.Batch(cmds...)
 }
 
+// HelpKeys returns the navigation bindings in selection mode, chat bindings otherwise
+func (p ChatPane) HelpKeys() []key.Binding {
+	if p.IsSelectionMode() {
+		return append(p.selectionView.HelpKeys(), p.keys.exitSelection)
+	}
+
+	return []key.Binding{
+		listNavigationKeys.up,
+		listNavigationKeys.down,
+		p.keys.selectionMode,
+		p.keys.copyLast,
+		p.keys.copyAll,
+		p.keys.reasoning,
+	}
+}
+
 func (p ChatPane) IsSelectionMode() bool {
 	return p.displayMode == selectionMode
 }
@@ -262,13 +344,20 @@ func (p ChatPane) initializePane(session sessions.Session) (ChatPane, tea.Cmd) {
 		p.isChatPaneReady = true
 	}
 
-	oldContent := util.GetMessagesAsPrettyString(session.Messages, paneWidth, p.colors)
+	p.renderMessages(session.Messages)
+	p.chatView.GotoBottom()
+	return p, nil
+}
+
+func (p *ChatPane) renderMessages(messages []util.MessageToSend) {
+	paneWidth, _ := util.CalcChatPaneSize(p.terminalWidth, p.terminalHeight, p.viewMode)
+	p.currentMessages = messages
+
+	oldContent := util.GetMessagesAsPrettyString(messages, paneWidth, p.colors, p.showReasoning)
 	if oldContent == "" {
 		oldContent = util.MotivationalMessage
 	}
-	rendered := util.GetVisualModeView(session.Messages, paneWidth, p.colors)
+	rendered := util.GetVisualModeView(messages, paneWidth, p.colors, p.showReasoning)
 	p.renderedContent = wrap.String(rendered, paneWidth)
 	p.chatView.SetContent(wrap.String(oldContent, paneWidth))
-	p.chatView.GotoBottom()
-	return p, nil
 }
@@ -0,0 +1,19 @@
+package panes
+
+import "github.com/charmbracelet/bubbles/key"
+
+// listNavigationKeys describe keys handled by the bubbles lists, viewport and inputs.
+// They are only shown in the help, so they are not part of the configurable key maps
+var listNavigationKeys = struct {
+	up     key.Binding
+	down   key.Binding
+	choose key.Binding
+	apply  key.Binding
+	cancel key.Binding
+}{
+	up:     key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "move up")),
+	down:   key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "move down")),
+	choose: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "choose")),
+	apply:  key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "apply")),
+	cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
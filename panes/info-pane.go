@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -11,16 +14,29 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tearingItUp786/nekot/config"
 	"github.com/tearingItUp786/nekot/sessions"
+	"github.com/tearingItUp786/nekot/settings"
+	"github.com/tearingItUp786/nekot/usage"
 	"github.com/tearingItUp786/nekot/util"
 )
 
-const notificationDisplayDurationSec = 2
+const (
+	notificationDisplayDurationSec = 2
+	promptCountDelay               = time.Millisecond * 150
+)
 
 const (
-	copiedLabelText     = "Copied to clipboard"
-	cancelledLabelText  = "Inference interrupted"
-	idleLabelText       = "IDLE"
-	processingLabelText = "Processing"
+	copiedLabelText          = "Copied to clipboard"
+	cancelledLabelText       = "Inference interrupted"
+	contextOverflowLabelText = "Context window exceeded, older messages won't fit"
+	budgetWarningLabelText   = "Monthly budget warning: %s of %s spent"
+	configReloadedLabelText  = "Config reloaded"
+	exportedLabelText        = "Exported to %s"
+	snippetSavedLabelText    = "Snippet saved"
+	snippetDeletedLabelText  = "Snippet deleted"
+	configErrorLabelText     = "Config error: %s"
+	idleLabelText            = "IDLE"
+	processingLabelText      = "Processing"
+	retryingLabelText        = "Retrying in %ds (%d/%d)"
 )
 
 var infoSpinnerStyle = lipgloss.NewStyle()
@@ -34,7 +50,13 @@ var defaultLabelStyle = lipgloss.NewStyle().
 
 type InfoPane struct {
 	sessionService *sessions.SessionService
+	usageService   *usage.UsageService
+	budget         config.BudgetConfig
 	currentSession sessions.Session
+	settings       util.Settings
+	contextLimits  map[string]int
+	azure          config.AzureConfig
+	systemMessage  string
 	colors         util.SchemeColors
 	spinner        spinner.Model
 
@@ -42,13 +64,24 @@ type InfoPane struct {
 	processingActiveLabel lipgloss.Style
 	promptTokensLablel    lipgloss.Style
 	completionTokensLabel lipgloss.Style
+	contextLabel          lipgloss.Style
+	costLabel             lipgloss.Style
 	notificationLabel     lipgloss.Style
 
-	showNotification bool
-	notification     util.Notification
-	isProcessing     bool
-	terminalWidth    int
-	terminalHeight   int
+	showNotification   bool
+	notification       util.Notification
+	configError        string
+	exportedPath       string
+	isProcessing       bool
+	promptTokens       int
+	promptRevision     int
+	conversationTokens int
+	sessionCost        float64
+	monthlyCost        float64
+	retry              util.RetryScheduled
+	retryAt            time.Time
+	terminalWidth      int
+	terminalHeight     int
 }
 
 func NewInfoPane(db *sql.DB, ctx context.Context) InfoPane {
@@ -59,40 +92,61 @@ func NewInfoPane(db *sql.DB, ctx context.Context) InfoPane {
 		fmt.Println("No config found")
 		panic("No config found in context")
 	}
-	colors := config.ColorScheme.GetColors()
-	spinner := initInfoSpinner()
+
+	pane := InfoPane{
+		spinner:        initInfoSpinner(),
+		sessionService: ss,
+		usageService:   usage.NewUsageService(db),
+		terminalWidth:  util.DefaultTerminalWidth,
+		terminalHeight: util.DefaultTerminalHeight,
+		configError:    getKeyConflictsError(),
+	}
+	pane.applyConfig(*config)
+	return pane
+}
+
+// key bindings are only applied on start, so their conflicts stay until a restart
+func getKeyConflictsError() string {
+	conflicts := util.GetKeyConflicts()
+	if len(conflicts) == 0 {
+		return ""
+	}
+	return "key " + strings.Join(conflicts, "; ")
+}
+
+// applyConfig is used on start and on every reload of the config file
+func (p *InfoPane) applyConfig(cfg config.Config) {
+	colors := cfg.ColorScheme.GetColors()
+	p.colors = colors
+	p.budget = cfg.Budget
+	p.contextLimits = cfg.ContextWindow.Limits
+	p.azure = cfg.Azure
+	p.systemMessage = cfg.SystemMessage
 
 	infoSpinnerStyle = infoSpinnerStyle.Copy().Foreground(colors.HighlightColor)
-	processingIdleLabel := defaultLabelStyle.Copy().
+	p.spinner.Style = infoSpinnerStyle
+	p.processingIdleLabel = defaultLabelStyle.Copy().
 		BorderLeftForeground(colors.HighlightColor).
 		Foreground(colors.DefaultTextColor)
-	processingActiveLabel := defaultLabelStyle.Copy().
+	p.processingActiveLabel = defaultLabelStyle.Copy().
 		BorderLeftForeground(colors.AccentColor).
 		Foreground(colors.DefaultTextColor)
-	promptTokensLablel := defaultLabelStyle.Copy().
+	p.promptTokensLablel = defaultLabelStyle.Copy().
 		BorderLeftForeground(colors.ActiveTabBorderColor).
 		Foreground(colors.DefaultTextColor)
-	completionTokensLabel := defaultLabelStyle.Copy().
+	p.completionTokensLabel = defaultLabelStyle.Copy().
 		BorderLeftForeground(colors.ActiveTabBorderColor).
 		Foreground(colors.DefaultTextColor)
-	notificationLabel := defaultLabelStyle.Copy().
+	p.contextLabel = defaultLabelStyle.Copy().
+		BorderLeftForeground(colors.MainColor).
+		Foreground(colors.DefaultTextColor)
+	p.costLabel = defaultLabelStyle.Copy().
+		BorderLeftForeground(colors.AccentColor).
+		Foreground(colors.DefaultTextColor)
+	p.notificationLabel = defaultLabelStyle.Copy().
 		Background(colors.NormalTabBorderColor).
 		BorderLeftForeground(colors.HighlightColor).
 		Foreground(colors.DefaultTextColor)
-
-	return InfoPane{
-		processingIdleLabel:   processingIdleLabel,
-		processingActiveLabel: processingActiveLabel,
-		promptTokensLablel:    promptTokensLablel,
-		completionTokensLabel: completionTokensLabel,
-		notificationLabel:     notificationLabel,
-
-		spinner:        spinner,
-		colors:         colors,
-		sessionService: ss,
-		terminalWidth:  util.DefaultTerminalWidth,
-		terminalHeight: util.DefaultTerminalHeight,
-	}
 }
 
 func initInfoSpinner() spinner.Model {
@@ -105,6 +159,29 @@ func initInfoSpinner() spinner.Model {
 
 type tickMsg struct{}
 
+type conversationTokensCounted struct {
+	sessionID int
+	model     string
+	tokens    int
+}
+
+type promptCountDue struct {
+	revision int
+	prompt   string
+}
+
+type promptTokensCounted struct {
+	revision int
+	tokens   int
+}
+
+// sentPromptCounted keeps the conversation tokens of the moment the prompt was sent,
+// the session may already contain the prompt once the count is done
+type sentPromptCounted struct {
+	conversationTokens int
+	promptTokens       int
+}
+
 func (p InfoPane) Init() tea.Cmd {
 	return nil
 }
@@ -122,9 +199,62 @@ func (p InfoPane) Update(msg tea.Msg) (InfoPane, tea.Cmd) {
 
 	case sessions.LoadDataFromDB:
 		p.currentSession = msg.Session
+		p.loadCosts()
+		cmds = append(cmds, p.countConversationTokens())
 
 	case sessions.UpdateCurrentSession:
 		p.currentSession = msg.Session
+		p.loadCosts()
+		cmds = append(cmds, p.countConversationTokens())
+
+	case settings.UpdateSettingsEvent:
+		p.settings = msg.Settings
+		p.promptTokens = 0
+		p.promptRevision++
+		cmds = append(cmds, p.countConversationTokens())
+
+	case conversationTokensCounted:
+		// results for a session or model that is no longer selected are stale
+		if msg.sessionID == p.currentSession.ID && msg.model == p.getModelName() {
+			p.conversationTokens = msg.tokens
+		}
+
+	case util.PromptInputChanged:
+		p.promptRevision++
+		revision := p.promptRevision
+		cmds = append(cmds, tea.Tick(promptCountDelay, func(time.Time) tea.Msg {
+			return promptCountDue{revision: revision, prompt: msg.Text}
+		}))
+
+	// counted once the typing pauses, not on every keystroke
+	case promptCountDue:
+		if msg.revision == p.promptRevision {
+			cmds = append(cmds, p.countPromptTokens(msg.prompt))
+		}
+
+	case promptTokensCounted:
+		// the prompt was changed or sent since the count started
+		if msg.revision == p.promptRevision {
+			p.promptTokens = msg.tokens
+		}
+
+	// the last debounced count may be missing or outdated, the sent prompt is counted once more
+	case util.PromptReady:
+		cmds = append(cmds, p.countSentPromptTokens(msg.Prompt))
+		p.promptTokens = 0
+		p.promptRevision++
+
+	case sentPromptCounted:
+		switch {
+		case p.isContextExceeded(msg.conversationTokens + msg.promptTokens):
+			p.notification = util.ContextOverflowNotification
+			p.showNotification = true
+			cmds = append(cmds, tickAfter(notificationDisplayDurationSec))
+		case usage.GetBudgetStatus(p.monthlyCost, p.budget) == usage.SoftLimitReached:
+			p.notification = util.BudgetWarningNotification
+			p.showNotification = true
+			cmds = append(cmds, tickAfter(notificationDisplayDurationSec))
+		}
 
 	case spinner.TickMsg:
 		p.spinner, cmd = p.spinner.Update(msg)
@@ -135,17 +265,41 @@ func (p InfoPane) Update(msg tea.Msg) (InfoPane, tea.Cmd) {
 		p.showNotification = true
 		cmds = append(cmds, tickAfter(notificationDisplayDurationSec))
 
+	case sessions.SessionExported:
+		p.exportedPath = msg.Path
+		p.notification = util.ExportedNotification
+		p.showNotification = true
+		cmds = append(cmds, tickAfter(notificationDisplayDurationSec))
+
 	case tickMsg:
 		p.showNotification = false
 
+	case config.ConfigChanged:
+		p.applyConfig(msg.Config)
+		p.configError = getKeyConflictsError()
+		p.notification = util.ConfigReloadedNotification
+		p.showNotification = true
+		cmds = append(cmds, tickAfter(notificationDisplayDurationSec), p.countConversationTokens())
+
+	case config.ConfigReloadFailed:
+		// shown until the file is fixed, the previous config stays in use meanwhile
+		p.configError = strings.ReplaceAll(msg.Err.Error(), "\n", " ")
+
+	case util.RetryScheduled:
+		p.retry = msg
+		p.retryAt = time.Now().Add(msg.Delay)
+
 	case util.ProcessingStateChanged:
 		p.isProcessing = msg.IsProcessing
+		p.retryAt = time.Time{}
 		if !msg.IsProcessing {
 			session, err := p.sessionService.GetSession(p.currentSession.ID)
 			if err != nil {
 				util.MakeErrorMsg(err.Error())
 			}
 			p.currentSession = session
+			p.loadCosts()
+			cmds = append(cmds, p.countConversationTokens())
 		} else {
 			cmds = append(cmds, p.spinner.Tick)
 		}
@@ -157,7 +311,13 @@ func (p InfoPane) Update(msg tea.Msg) (InfoPane, tea.Cmd) {
 func (p InfoPane) View() string {
 	paneWidth, _ := util.CalcSettingsPaneSize(p.terminalWidth, p.terminalHeight)
 	var processingLabel string
-	if p.isProcessing {
+	if p.isProcessing && time.Now().Before(p.retryAt) {
+		// spinner ticks keep the countdown updated
+		secondsLeft := int(math.Ceil(time.Until(p.retryAt).Seconds()))
+		processingLabel = p.processingActiveLabel.Copy().
+			BorderLeftForeground(p.colors.ErrorColor).
+			Render(fmt.Sprintf(retryingLabelText, secondsLeft, p.retry.Attempt, p.retry.MaxAttempts))
+	} else if p.isProcessing {
 		processingLabel = p.processingActiveLabel.Render(processingLabelText + p.spinner.View())
 	} else {
 		processingLabel = p.processingIdleLabel.Render(idleLabelText)
@@ -166,13 +326,30 @@ func (p InfoPane) View() string {
 	promptTokensLablel := p.promptTokensLablel.Render(fmt.Sprintf("IN: %d", p.currentSession.PromptTokens))
 	completionTokensLabel := p.completionTokensLabel.Render(fmt.Sprintf("OUT: %d", p.currentSession.CompletionTokens))
 
-	firstRow := processingLabel
+	strategyLabel := p.contextLabel.Render(p.currentSession.ContextStrategy.Label())
+	costLabel := p.costLabelView()
+
+	firstRow := lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		processingLabel,
+		p.contextUsageView(),
+	)
 	secondRow := lipgloss.JoinHorizontal(
 		lipgloss.Left,
 		promptTokensLablel,
 		completionTokensLabel,
+		costLabel,
+		strategyLabel,
 	)
 
+	if p.configError != "" {
+		secondRow = p.notificationLabel.
+			Background(p.colors.ErrorColor).
+			Align(lipgloss.Left).
+			Width(paneWidth - 1).
+			Render(truncateLabel(fmt.Sprintf(configErrorLabelText, p.configError), max(paneWidth-4, 4)))
+	}
+
 	if p.showNotification {
 		notificationLabel := lipgloss.NewStyle()
 		notificationText := ""
@@ -190,6 +367,46 @@ func (p InfoPane) View() string {
 				Background(p.colors.ErrorColor).
 				Align(lipgloss.Left).
 				Width(paneWidth - 1)
+		case util.ContextOverflowNotification:
+			notificationText = contextOverflowLabelText
+			notificationLabel = p.notificationLabel.
+				Background(p.colors.ErrorColor).
+				Align(lipgloss.Left).
+				Width(paneWidth - 1)
+		case util.ConfigReloadedNotification:
+			notificationText = configReloadedLabelText
+			notificationLabel = p.notificationLabel.
+				Background(p.colors.NormalTabBorderColor).
+				Align(lipgloss.Left).
+				Width(paneWidth - 1)
+		case util.SnippetSavedNotification:
+			notificationText = snippetSavedLabelText
+			notificationLabel = p.notificationLabel.
+				Background(p.colors.NormalTabBorderColor).
+				Align(lipgloss.Left).
+				Width(paneWidth - 1)
+		case util.SnippetDeletedNotification:
+			notificationText = snippetDeletedLabelText
+			notificationLabel = p.notificationLabel.
+				Background(p.colors.NormalTabBorderColor).
+				Align(lipgloss.Left).
+				Width(paneWidth - 1)
+		case util.ExportedNotification:
+			// the file is written into the working directory, so its name is enough
+			notificationText = truncateLabel(fmt.Sprintf(exportedLabelText, filepath.Base(p.exportedPath)), max(paneWidth-4, 4))
+			notificationLabel = p.notificationLabel.
+				Background(p.colors.NormalTabBorderColor).
+				Align(lipgloss.Left).
+				Width(paneWidth - 1)
+		case util.BudgetWarningNotification:
+			notificationText = fmt.Sprintf(
+				budgetWarningLabelText,
+				usage.FormatCost(p.monthlyCost),
+				usage.FormatCost(p.budget.MonthlySoftLimit))
+			notificationLabel = p.notificationLabel.
+				Background(p.colors.ErrorColor).
+				Align(lipgloss.Left).
+				Width(paneWidth - 1)
 		}
 
 		firstRow = lipgloss.JoinHorizontal(
@@ -200,6 +417,7 @@ func (p InfoPane) View() string {
 		secondRow = ""
 	}
 
+	rowStyle := lipgloss.NewStyle().MaxWidth(paneWidth)
 	return lipgloss.NewStyle().
 		BorderStyle(lipgloss.ThickBorder()).
 		BorderForeground(p.colors.NormalTabBorderColor).
@@ -207,12 +425,137 @@ func (p InfoPane) View() string {
 		Render(
 			lipgloss.JoinVertical(
 				lipgloss.Left,
-				firstRow,
-				secondRow,
+				rowStyle.Render(firstRow),
+				rowStyle.Render(secondRow),
 			),
 		)
 }
 
+func (p *InfoPane) loadCosts() {
+	sessionCost, err := p.usageService.GetSessionCost(p.currentSession.ID)
+	if err != nil {
+		util.Log("Failed to load session cost", err)
+	}
+	monthlyCost, err := p.usageService.GetMonthlyCost()
+	if err != nil {
+		util.Log("Failed to load monthly cost", err)
+	}
+
+	p.sessionCost = sessionCost
+	p.monthlyCost = monthlyCost
+}
+
+// shows the session cost, monthly spending is added once a budget is set
+func (p InfoPane) costLabelView() string {
+	text := usage.FormatCost(p.sessionCost)
+	limit := max(p.budget.MonthlySoftLimit, p.budget.MonthlyHardLimit)
+	if limit == 0 {
+		return p.costLabel.Render(text)
+	}
+
+	label := p.costLabel
+	if usage.GetBudgetStatus(p.monthlyCost, p.budget) != usage.WithinBudget {
+		label = label.Copy().BorderLeftForeground(p.colors.ErrorColor)
+	}
+
+	return label.Render(fmt.Sprintf("%s (%s/%s)", text, usage.FormatCost(p.monthlyCost), usage.FormatCost(limit)))
+}
+
+// counting a long conversation takes a while, so it is done outside of the update loop
+func (p InfoPane) countConversationTokens() tea.Cmd {
+	sessionID := p.currentSession.ID
+	model := p.getModelName()
+	messages := p.currentSession.Messages
+	systemMessage := p.systemMessage
+	if p.currentSession.SystemMessage != "" {
+		systemMessage = p.currentSession.SystemMessage
+	}
+
+	return func() tea.Msg {
+		util.WaitForTokenizer(model)
+		tokens := util.CountMessagesTokens(messages, model) + util.CountTokens(systemMessage, model)
+		return conversationTokensCounted{sessionID: sessionID, model: model, tokens: tokens}
+	}
+}
+
+func (p InfoPane) countPromptTokens(prompt string) tea.Cmd {
+	revision := p.promptRevision
+	model := p.getModelName()
+
+	return func() tea.Msg {
+		util.WaitForTokenizer(model)
+		return promptTokensCounted{revision: revision, tokens: util.CountTokens(prompt, model)}
+	}
+}
+
+func (p InfoPane) countSentPromptTokens(prompt string) tea.Cmd {
+	conversationTokens := p.conversationTokens
+	model := p.getModelName()
+
+	return func() tea.Msg {
+		util.WaitForTokenizer(model)
+		return sentPromptCounted{conversationTokens: conversationTokens, promptTokens: util.CountTokens(prompt, model)}
+	}
+}
+
+// Azure deployments are counted and limited as the models behind them
+func (p InfoPane) getModelName() string {
+	return p.azure.GetModelName(p.settings.Model)
+}
+
+// the answer is cut or the request is rejected once the conversation,
+// the prompt and the max tokens of the answer don't fit into the context window
+func (p InfoPane) isContextExceeded(usedTokens int) bool {
+	limit := util.GetContextLimit(p.getModelName(), p.contextLimits)
+	if limit == 0 {
+		return false
+	}
+	return usedTokens+p.settings.MaxTokens > limit
+}
+
+// shows how much of the model context window the session messages and the typed prompt take
+func (p InfoPane) contextUsageView() string {
+	usedTokens := p.conversationTokens + p.promptTokens
+	limit := util.GetContextLimit(p.getModelName(), p.contextLimits)
+
+	promptLabel := ""
+	if p.promptTokens > 0 {
+		promptLabel = p.promptTokensLablel.Render(fmt.Sprintf("PROMPT: %s", formatTokensAmount(p.promptTokens)))
+	}
+
+	if limit == 0 {
+		return lipgloss.JoinHorizontal(
+			lipgloss.Left,
+			p.contextLabel.Render(fmt.Sprintf("CTX: %s", formatTokensAmount(usedTokens))),
+			promptLabel,
+		)
+	}
+
+	label := p.contextLabel
+	switch {
+	case usedTokens > limit:
+		label = label.Copy().BorderLeftForeground(p.colors.ErrorColor).Foreground(p.colors.ErrorColor)
+	case p.isContextExceeded(usedTokens):
+		label = label.Copy().BorderLeftForeground(p.colors.ErrorColor)
+	}
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		label.Render(fmt.Sprintf(
+			"CTX: %s/%s",
+			formatTokensAmount(usedTokens),
+			formatTokensAmount(limit))),
+		promptLabel,
+	)
+}
+
+func formatTokensAmount(tokens int) string {
+	if tokens < 1000 {
+		return fmt.Sprint(tokens)
+	}
+	return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+}
+
 func tickAfter(seconds int) tea.Cmd {
 	return tea.Tick(time.Second*time.Duration(seconds), func(t time.Time) tea.Msg {
 		return tickMsg{}
@@ -2,7 +2,9 @@ package panes
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/atotto/clipboard"
@@ -12,6 +14,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/history"
+	"github.com/tearingItUp786/nekot/sessions"
 	"github.com/tearingItUp786/nekot/util"
 )
 
@@ -19,6 +23,9 @@ const ResponseWaitingMsg = "> Please wait ..."
 const InitializingMsg = "Components initializing ..."
 const PlaceholderMsg = "Press i to type. Use ctrl+e to expand/collapse editor"
 
+// sent prompts kept in memory for up/down and the search
+const promptHistoryLimit = 500
+
 type keyMap struct {
 	insert    key.Binding
 	clear     key.Binding
@@ -26,6 +33,12 @@ type keyMap struct {
 	paste     key.Binding
 	pasteCode key.Binding
 	enter     key.Binding
+	approve   key.Binding
+	decline   key.Binding
+
+	previousPrompt key.Binding
+	nextPrompt     key.Binding
+	searchHistory  key.Binding
 }
 
 var defaultKeyMap = keyMap{
@@ -35,6 +48,31 @@ var defaultKeyMap = keyMap{
 	paste:     key.NewBinding(key.WithKeys(tea.KeyCtrlV.String()), key.WithHelp("ctrl+v", "insert text from clipboard")),
 	pasteCode: key.NewBinding(key.WithKeys(tea.KeyCtrlS.String()), key.WithHelp("ctrl+s", "insert code block from clipboard")),
 	enter:     key.NewBinding(key.WithKeys(tea.KeyEnter.String()), key.WithHelp("enter", "send prompt")),
+	approve:   key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "run requested tool calls")),
+	decline:   key.NewBinding(key.WithKeys("n", tea.KeyEsc.String()), key.WithHelp("n/esc", "decline requested tool calls")),
+
+	previousPrompt: key.NewBinding(key.WithKeys(tea.KeyUp.String()), key.WithHelp("↑", "previous prompt")),
+	nextPrompt:     key.NewBinding(key.WithKeys(tea.KeyDown.String()), key.WithHelp("↓", "next prompt")),
+	searchHistory:  key.NewBinding(key.WithKeys(tea.KeyCtrlG.String()), key.WithHelp("ctrl+g", "search prompt history")),
+}
+
+func init() {
+	util.RegisterKeyBindings(util.PromptKeys, util.KeyBindings{
+		"insert":    &defaultKeyMap.insert,
+		"clear":     &defaultKeyMap.clear,
+		"exit":      &defaultKeyMap.exit,
+		"paste":     &defaultKeyMap.paste,
+		"pasteCode": &defaultKeyMap.pasteCode,
+		"enter":     &defaultKeyMap.enter,
+
+		"previousPrompt": &defaultKeyMap.previousPrompt,
+		"nextPrompt":     &defaultKeyMap.nextPrompt,
+		"searchHistory":  &defaultKeyMap.searchHistory,
+	})
+	util.RegisterKeyBindings(util.ToolApprovalKeys, util.KeyBindings{
+		"approve": &defaultKeyMap.approve,
+		"decline": &defaultKeyMap.decline,
+	})
 }
 
 type PromptPane struct {
@@ -45,6 +83,20 @@ type PromptPane struct {
 	colors     util.SchemeColors
 	keys       keyMap
 
+	pendingToolCalls []util.ToolCall
+
+	historyService *history.HistoryService
+	sessionService *sessions.SessionService
+	// the draft is saved for this session when another one becomes active
+	sessionID int
+	// a draft waiting for the view mode change, the input would put it on a single line
+	restoredDraft *string
+	// newest first, historyIdx is -1 until a prompt is recalled
+	promptHistory []string
+	historyIdx    int
+	// the recalled prompt as the input shows it, typing over it ends the browsing
+	historyValue string
+
 	viewMode       util.ViewMode
 	isSessionIdle  bool
 	isFocused      bool
@@ -53,27 +105,27 @@ type PromptPane struct {
 	ready          bool
 }
 
-func NewPromptPane(ctx context.Context) PromptPane {
+type promptHistoryLoaded struct {
+	prompts []string
+}
+
+func NewPromptPane(db *sql.DB, ctx context.Context) PromptPane {
 	config, ok := config.FromContext(ctx)
 	if !ok {
 		fmt.Println("No config found")
 		panic("No config found in context")
 	}
 
-	colors := config.ColorScheme.GetColors()
-
 	input := textinput.New()
 	input.Placeholder = InitializingMsg
-	input.PromptStyle = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
 	input.CharLimit = 0
 	input.Width = 0
+	input.ShowSuggestions = true
+	input.SetSuggestions(getSlashCompletions())
 
 	textEditor := textarea.New()
 	textEditor.Placeholder = PlaceholderMsg
-	textEditor.FocusedStyle.Prompt = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
 	textEditor.FocusedStyle.CursorLine.Background(lipgloss.NoColor{})
-	textEditor.FocusedStyle.EndOfBuffer = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
-	textEditor.FocusedStyle.LineNumber = lipgloss.NewStyle().Foreground(colors.AccentColor)
 
 	textEditor.EndOfBufferCharacter = rune(' ')
 	textEditor.ShowLineNumbers = true
@@ -84,13 +136,14 @@ func NewPromptPane(ctx context.Context) PromptPane {
 	container := lipgloss.NewStyle().
 		AlignVertical(lipgloss.Bottom).
 		BorderStyle(lipgloss.ThickBorder()).
-		BorderForeground(colors.ActiveTabBorderColor).
 		MarginTop(util.PromptPaneMarginTop)
 
-	return PromptPane{
+	pane := PromptPane{
 		keys:           defaultKeyMap,
+		historyService: history.NewHistoryService(db),
+		sessionService: sessions.NewSessionService(db),
+		historyIdx:     -1,
 		viewMode:       util.NormalMode,
-		colors:         colors,
 		input:          input,
 		textEditor:     textEditor,
 		container:      container,
@@ -100,18 +153,77 @@ func NewPromptPane(ctx context.Context) PromptPane {
 		terminalWidth:  util.DefaultTerminalWidth,
 		terminalHeight: util.DefaultTerminalHeight,
 	}
+	pane.applyColors(config.ColorScheme.GetColors())
+	return pane
+}
+
+func (p *PromptPane) applyColors(colors util.SchemeColors) {
+	p.colors = colors
+
+	borderColor := colors.NormalTabBorderColor
+	if p.isFocused {
+		borderColor = colors.ActiveTabBorderColor
+	}
+	p.container = p.container.Copy().BorderForeground(borderColor)
+	p.input.PromptStyle = lipgloss.NewStyle().Foreground(borderColor)
+
+	p.textEditor.FocusedStyle.Prompt = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
+	p.textEditor.FocusedStyle.EndOfBuffer = lipgloss.NewStyle().Foreground(colors.ActiveTabBorderColor)
+	p.textEditor.FocusedStyle.LineNumber = lipgloss.NewStyle().Foreground(colors.AccentColor)
 }
 
 func (p PromptPane) Init() tea.Cmd {
-	return p.input.Cursor.BlinkCmd()
+	return tea.Batch(p.input.Cursor.BlinkCmd(), p.loadPromptHistory)
 }
 
+func (p PromptPane) loadPromptHistory() tea.Msg {
+	prompts, err := p.historyService.GetPrompts(promptHistoryLimit)
+	if err != nil {
+		return util.ErrorEvent{Message: err.Error()}
+	}
+	return promptHistoryLoaded{prompts: prompts}
+}
+
+// Update notifies about every change of the typed prompt, so that its tokens can be counted live
 func (p PromptPane) Update(msg tea.Msg) (PromptPane, tea.Cmd) {
+	previousValue := p.value()
+	p, cmd := p.update(msg)
+
+	if value := p.value(); value != previousValue {
+		return p, tea.Batch(cmd, util.SendPromptInputChangedMsg(value))
+	}
+	return p, cmd
+}
+
+func (p PromptPane) value() string {
+	if p.viewMode == util.TextEditMode {
+		return p.textEditor.Value()
+	}
+	return p.input.Value()
+}
+
+func (p PromptPane) update(msg tea.Msg) (PromptPane, tea.Cmd) {
 	var (
 		cmd  tea.Cmd
 		cmds []tea.Cmd
 	)
 
+	// up and down recall prompts instead of reaching the input, as long as the prompt is empty or recalled
+	if msg, ok := msg.(tea.KeyMsg); ok && p.IsTypingInProcess() && p.isSessionIdle && p.isBrowsingHistory() {
+		if p.value() != p.historyValue {
+			p.historyIdx = -1
+		}
+
+		switch {
+		case key.Matches(msg, p.keys.previousPrompt) && p.isCursorOnEdgeLine(true):
+			p.recallPrompt(p.historyIdx + 1)
+			return p, nil
+		case key.Matches(msg, p.keys.nextPrompt) && p.isCursorOnEdgeLine(false):
+			p.recallPrompt(p.historyIdx - 1)
+			return p, nil
+		}
+	}
+
 	if p.isFocused && p.inputMode == util.PromptInsertMode && p.isSessionIdle {
 		switch p.viewMode {
 		case util.TextEditMode:
@@ -138,20 +250,62 @@ func (p PromptPane) Update(msg tea.Msg) (PromptPane, tea.Cmd) {
 			p.input.Blur()
 			p.input.Reset()
 
-			p.textEditor.SetValue(currentInput)
+			p.textEditor.SetValue(p.takeRestoredDraft(currentInput))
 		} else {
 			p.input.Width = w
 			currentInput := p.textEditor.Value()
 			p.textEditor.Blur()
 			p.textEditor.Reset()
 
-			p.input.SetValue(currentInput)
+			p.input.SetValue(p.takeRestoredDraft(currentInput))
 		}
 		p.container = p.container.Copy().MaxWidth(p.terminalWidth).Width(w)
 
+	case sessions.LoadDataFromDB:
+		p.sessionID = msg.Session.ID
+		cmds = append(cmds, p.restoreDraft(msg.Session))
+
+	case sessions.UpdateCurrentSession:
+		// the message also comes for changes of the current session, its draft is still in the prompt then
+		if msg.Session.ID != p.sessionID {
+			p.SaveDraft()
+			p.sessionID = msg.Session.ID
+			cmds = append(cmds, p.restoreDraft(msg.Session))
+		}
+
+	case promptHistoryLoaded:
+		p.promptHistory = msg.prompts
+
+	case util.PromptHistoryPicked:
+		p.historyIdx = -1
+		p.inputMode = util.PromptInsertMode
+		switch p.viewMode {
+		case util.TextEditMode:
+			p.textEditor.SetValue(msg.Prompt)
+			cmds = append(cmds, p.textEditor.Focus())
+		default:
+			p.input.SetValue(msg.Prompt)
+			cmds = append(cmds, p.input.Focus())
+		}
+
+	case util.PromptInsertRequested:
+		if p.viewMode != util.TextEditMode {
+			break
+		}
+		p.inputMode = util.PromptInsertMode
+		p.textEditor.InsertString(msg.Text)
+		cmds = append(cmds, p.textEditor.Focus())
+
 	case util.ProcessingStateChanged:
 		p.isSessionIdle = msg.IsProcessing == false
 
+	case sessions.ToolCallsRequested:
+		p.pendingToolCalls = msg.ToolCalls
+		p.inputMode = util.PromptNormalMode
+
+	case config.ConfigChanged:
+		p.applyColors(msg.Config.ColorScheme.GetColors())
+
 	case util.FocusEvent:
 		p.isFocused = msg.IsFocused
 
@@ -186,6 +340,10 @@ func (p PromptPane) Update(msg tea.Msg) (PromptPane, tea.Cmd) {
 			break
 		}
 
+		if p.IsAwaitingToolConfirmation() {
+			return p, p.handleToolCallsConfirmation(msg)
+		}
+
 		switch {
 
 		case key.Matches(msg, p.keys.insert):
@@ -230,6 +388,11 @@ func (p PromptPane) Update(msg tea.Msg) (PromptPane, tea.Cmd) {
 				}
 			}
 
+		case key.Matches(msg, p.keys.searchHistory):
+			if p.isFocused && p.isSessionIdle {
+				return p, util.SendPromptHistorySearchRequestedMsg(p.promptHistory)
+			}
+
 		case key.Matches(msg, p.keys.enter):
 			if p.isFocused && p.isSessionIdle {
 
@@ -239,18 +402,21 @@ func (p PromptPane) Update(msg tea.Msg) (PromptPane, tea.Cmd) {
 						promptText := p.textEditor.Value()
 						p.textEditor.SetValue("")
 						p.textEditor.Blur()
-						return p, tea.Batch(
-							util.SendPromptReadyMsg(promptText),
-							util.SendViewModeChangedMsg(util.NormalMode))
+						p.addToHistory(promptText)
+						// commands may open the editor again, e.g. to insert a snippet
+						return p, tea.Sequence(
+							util.SendViewModeChangedMsg(util.NormalMode),
+							sendPrompt(promptText))
 					}
 				default:
 					promptText := p.input.Value()
 					p.input.SetValue("")
 					p.input.Blur()
+					p.addToHistory(promptText)
 
 					p.inputMode = util.PromptNormalMode
 
-					return p, util.SendPromptReadyMsg(promptText)
+					return p, sendPrompt(promptText)
 				}
 			}
 
@@ -271,6 +437,120 @@ func (p PromptPane) Update(msg tea.Msg) (PromptPane, tea.Cmd) {
 	return p, tea.Batch(cmds...)
 }
 
+// sendPrompt runs slash commands, everything else goes to the model
+func sendPrompt(prompt string) tea.Cmd {
+	if isSlashCommand(prompt) {
+		return runSlashCommand(prompt)
+	}
+	return util.SendPromptReadyMsg(unescapeSlash(prompt))
+}
+
+// SaveDraft keeps the unsent prompt of the current session together with the editor mode
+func (p PromptPane) SaveDraft() {
+	if p.sessionID == 0 {
+		return
+	}
+
+	err := p.sessionService.UpdateSessionDraft(p.sessionID, p.value(), p.viewMode == util.TextEditMode)
+	if err != nil {
+		util.Log("Draft not saved:", err)
+	}
+}
+
+// restoreDraft switches the view mode when the draft was written in the other one,
+// an empty draft leaves the view mode as it is
+func (p *PromptPane) restoreDraft(session sessions.Session) tea.Cmd {
+	p.historyIdx = -1
+	isTextEditMode := p.viewMode == util.TextEditMode
+
+	if session.Draft != "" && session.DraftInEditor != isTextEditMode {
+		draft := session.Draft
+		p.restoredDraft = &draft
+
+		mode := util.NormalMode
+		if session.DraftInEditor {
+			mode = util.TextEditMode
+		}
+		return util.SendViewModeChangedMsg(mode)
+	}
+
+	switch p.viewMode {
+	case util.TextEditMode:
+		p.textEditor.SetValue(session.Draft)
+	default:
+		p.input.SetValue(session.Draft)
+	}
+	return nil
+}
+
+// takeRestoredDraft returns the draft waiting for the view mode change instead of the typed prompt
+func (p *PromptPane) takeRestoredDraft(currentInput string) string {
+	if p.restoredDraft == nil {
+		return currentInput
+	}
+
+	draft := *p.restoredDraft
+	p.restoredDraft = nil
+	return draft
+}
+
+func (p PromptPane) isBrowsingHistory() bool {
+	return p.value() == "" || p.historyIdx >= 0 && p.value() == p.historyValue
+}
+
+// isCursorOnEdgeLine leaves up and down to the editor while they move the cursor between
+// the lines of a multiline prompt, the history is reached from the first or the last line
+func (p PromptPane) isCursorOnEdgeLine(first bool) bool {
+	if p.viewMode != util.TextEditMode || p.value() == "" {
+		return true
+	}
+	if first {
+		return p.textEditor.Line() == 0
+	}
+	return p.textEditor.Line() == p.textEditor.LineCount()-1
+}
+
+// recallPrompt clears the prompt when moving past the newest one, older than the oldest is ignored
+func (p *PromptPane) recallPrompt(idx int) {
+	if idx >= len(p.promptHistory) {
+		return
+	}
+
+	p.historyIdx = max(idx, -1)
+	prompt := ""
+	if p.historyIdx >= 0 {
+		prompt = p.promptHistory[p.historyIdx]
+	}
+
+	switch p.viewMode {
+	case util.TextEditMode:
+		p.textEditor.SetValue(prompt)
+	default:
+		// the input puts multiline prompts on a single line
+		p.input.SetValue(prompt)
+		p.input.CursorEnd()
+	}
+	p.historyValue = p.value()
+}
+
+// addToHistory moves a prompt sent before to the front
+func (p *PromptPane) addToHistory(prompt string) {
+	p.historyIdx = -1
+	if strings.TrimSpace(prompt) == "" {
+		return
+	}
+
+	if err := p.historyService.AddPrompt(prompt); err != nil {
+		util.Log("Prompt history not saved:", err)
+	}
+
+	p.promptHistory = slices.DeleteFunc(p.promptHistory, func(sent string) bool { return sent == prompt })
+	p.promptHistory = append([]string{prompt}, p.promptHistory...)
+	if len(p.promptHistory) > promptHistoryLimit {
+		p.promptHistory = p.promptHistory[:promptHistoryLimit]
+	}
+}
+
 func (p *PromptPane) insertBufferContentAsCodeBlock() {
 	buffer, _ := clipboard.ReadAll()
 	currentInput := p.textEditor.Value()
@@ -285,6 +565,66 @@ func (p *PromptPane) insertBufferContentAsCodeBlock() {
 	p.textEditor.SetCursor(0)
 }
 
+func (p *PromptPane) handleToolCallsConfirmation(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, p.keys.approve):
+		p.pendingToolCalls = nil
+		return sessions.SendToolCallsDecisionMsg(true)
+
+	case key.Matches(msg, p.keys.decline):
+		p.pendingToolCalls = nil
+		return sessions.SendToolCallsDecisionMsg(false)
+	}
+
+	return nil
+}
+
+func (p PromptPane) toolCallsConfirmationView() string {
+	calls := []string{}
+	for _, toolCall := range p.pendingToolCalls {
+		calls = append(calls, fmt.Sprintf("%s %s", toolCall.Function.Name, toolCall.Function.Arguments))
+	}
+
+	question := fmt.Sprintf("🔧 Run %s? [y/n]", strings.Join(calls, ", "))
+	w, _ := util.CalcPromptPaneSize(p.terminalWidth, p.terminalHeight, false)
+
+	return lipgloss.NewStyle().
+		Foreground(p.colors.HighlightColor).
+		MaxWidth(w).
+		Render(question)
+}
+
+func (p PromptPane) IsAwaitingToolConfirmation() bool {
+	return len(p.pendingToolCalls) > 0
+}
+
+// HelpKeys returns the bindings that can be used in the current input mode
+func (p PromptPane) HelpKeys() []key.Binding {
+	if p.IsAwaitingToolConfirmation() {
+		return []key.Binding{p.keys.approve, p.keys.decline}
+	}
+
+	if p.inputMode == util.PromptInsertMode {
+		keys := []key.Binding{p.keys.exit, p.keys.paste, p.keys.clear, p.keys.searchHistory}
+		switch p.viewMode {
+		case util.TextEditMode:
+			return append(keys, p.keys.pasteCode)
+		default:
+			return append([]key.Binding{p.keys.enter}, keys...)
+		}
+	}
+
+	return []key.Binding{p.keys.insert, p.keys.enter, p.keys.paste, p.keys.clear, p.keys.searchHistory}
+}
+
+// SlashSuggestions describes the commands matching the typed prompt, they are shown under the input
+func (p PromptPane) SlashSuggestions() []SlashSuggestion {
+	if !p.IsTypingInProcess() {
+		return nil
+	}
+	return getSlashSuggestions(p.value())
+}
+
 func (p PromptPane) IsTypingInProcess() bool {
 	return p.isFocused && p.inputMode == util.PromptInsertMode
 }
@@ -296,6 +636,10 @@ func (p PromptPane) Enable() PromptPane {
 }
 
 func (p PromptPane) View() string {
+	if p.IsAwaitingToolConfirmation() {
+		return p.container.Render(p.toolCallsConfirmationView())
+	}
+
 	if p.isSessionIdle {
 		content := p.input.View()
 		if p.viewMode == util.TextEditMode {
@@ -30,19 +30,32 @@ const (
 )
 
 type sessionsKeyMap struct {
-	addNew key.Binding
-	delete key.Binding
-	rename key.Binding
-	cancel key.Binding
-	apply  key.Binding
+	addNew          key.Binding
+	delete          key.Binding
+	rename          key.Binding
+	cancel          key.Binding
+	apply           key.Binding
+	contextStrategy key.Binding
 }
 
 var defaultSessionsKeyMap = sessionsKeyMap{
-	delete: key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete session")),
-	rename: key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "rename session")),
-	cancel: key.NewBinding(key.WithKeys(tea.KeyEsc.String()), key.WithHelp("esc", "cancel action")),
-	apply:  key.NewBinding(key.WithKeys(tea.KeyEnter.String()), key.WithHelp("esc", "switch to session/apply renaming")),
-	addNew: key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "add new session")),
+	delete:          key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete session")),
+	rename:          key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "rename session")),
+	cancel:          key.NewBinding(key.WithKeys(tea.KeyEsc.String()), key.WithHelp("esc", "cancel action")),
+	apply:           key.NewBinding(key.WithKeys(tea.KeyEnter.String()), key.WithHelp("enter", "switch to session/apply renaming")),
+	addNew:          key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "add new session")),
+	contextStrategy: key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "switch context strategy")),
+}
+
+func init() {
+	util.RegisterKeyBindings(util.SessionsKeys, util.KeyBindings{
+		"addNew":          &defaultSessionsKeyMap.addNew,
+		"delete":          &defaultSessionsKeyMap.delete,
+		"rename":          &defaultSessionsKeyMap.rename,
+		"cancel":          &defaultSessionsKeyMap.cancel,
+		"apply":           &defaultSessionsKeyMap.apply,
+		"contextStrategy": &defaultSessionsKeyMap.contextStrategy,
+	})
 }
 
 type SessionsPane struct {
@@ -120,6 +133,46 @@ func (p SessionsPane) Update(msg tea.Msg) (SessionsPane, tea.Cmd) {
 		p.isFocused = msg.IsFocused
 		p.operationMode = defaultMode
 
+	case util.NewSessionMsg:
+		p.operationMode = defaultMode
+		p.operationTargetId = NoTargetSession
+		cmds = append(cmds, p.addNewSession())
+
+	case sessions.RenameSessionRequested:
+		err := p.sessionService.UpdateSessionName(p.currentSessionId, msg.Name)
+		if err != nil {
+			return p, util.MakeErrorMsg(err.Error())
+		}
+		p.updateSessionsList()
+		cmds = append(cmds, p.reloadCurrentSession())
+
+	case sessions.SystemMessageChangeRequested:
+		err := p.sessionService.UpdateSessionSystemMessage(p.currentSessionId, msg.SystemMessage)
+		if err != nil {
+			return p, util.MakeErrorMsg(err.Error())
+		}
+		cmds = append(cmds, p.reloadCurrentSession())
+
+	case sessions.ClearSessionRequested:
+		// the summary describes the removed messages, so it goes away with them
+		err := p.sessionService.UpdateSessionMessages(p.currentSessionId, []util.MessageToSend{})
+		if err == nil {
+			err = p.sessionService.UpdateSessionContextSummary(p.currentSessionId, "", 0)
+		}
+		if err != nil {
+			return p, util.MakeErrorMsg(err.Error())
+		}
+		cmds = append(cmds, p.reloadCurrentSession())
+
+	case config.ConfigChanged:
+		p.colors = msg.Config.ColorScheme.GetColors()
+		if p.sessionsListReady {
+			// list styles are set on creation, so the list is rebuilt with the new colors
+			listItems := constructSessionsListItems(p.sessionsListData, p.currentSessionId)
+			w, h := util.CalcSessionsListSize(p.terminalWidth, p.terminalHeight)
+			p.sessionsList = components.NewSessionsList(listItems, w, h, p.colors)
+		}
+
 	case tea.WindowSizeMsg:
 		p.terminalWidth = msg.Width
 		p.terminalHeight = msg.Height
@@ -197,14 +250,7 @@ func (p *SessionsPane) handleDefaultMode(msg tea.KeyMsg) tea.Cmd {
 	switch {
 
 	case key.Matches(msg, p.keyMap.addNew):
-
-		currentTime := time.Now()
-		formattedTime := currentTime.Format(time.ANSIC)
-		defaultSessionName := fmt.Sprintf("%s", formattedTime)
-		newSession, _ := p.sessionService.InsertNewSession(defaultSessionName, []util.MessageToSend{})
-
-		cmd = p.handleUpdateCurrentSession(newSession)
-		p.updateSessionsList()
+		cmd = p.addNewSession()
 
 	case key.Matches(msg, p.keyMap.apply):
 		i, ok := p.sessionsList.GetSelectedItem()
@@ -218,6 +264,15 @@ func (p *SessionsPane) handleDefaultMode(msg tea.KeyMsg) tea.Cmd {
 			cmd = p.handleUpdateCurrentSession(session)
 		}
 
+	case key.Matches(msg, p.keyMap.contextStrategy):
+		strategy := p.currentSession.ContextStrategy.Next()
+		err := p.sessionService.UpdateSessionContextStrategy(p.currentSessionId, strategy)
+		if err != nil {
+			return util.MakeErrorMsg(err.Error())
+		}
+
+		cmd = p.reloadCurrentSession()
+
 	case key.Matches(msg, p.keyMap.rename):
 		p.operationMode = editMode
 		ti := textinput.New()
@@ -253,6 +308,27 @@ func (p *SessionsPane) handleDefaultMode(msg tea.KeyMsg) tea.Cmd {
 	return cmd
 }
 
+func (p *SessionsPane) addNewSession() tea.Cmd {
+	currentTime := time.Now()
+	formattedTime := currentTime.Format(time.ANSIC)
+	defaultSessionName := fmt.Sprintf("%s", formattedTime)
+	newSession, _ := p.sessionService.InsertNewSession(defaultSessionName, []util.MessageToSend{})
+
+	cmd := p.handleUpdateCurrentSession(newSession)
+	p.updateSessionsList()
+	return cmd
+}
+
+// reloadCurrentSession lets the other panes know about changes of the current session
+func (p *SessionsPane) reloadCurrentSession() tea.Cmd {
+	session, err := p.sessionService.GetSession(p.currentSessionId)
+	if err != nil {
+		return util.MakeErrorMsg(err.Error())
+	}
+
+	return p.handleUpdateCurrentSession(session)
+}
+
 func (p *SessionsPane) handleUpdateCurrentSession(session sessions.Session) tea.Cmd {
 	p.currentSession = session
 	p.userService.UpdateUserCurrentActiveSession(1, session.ID)
@@ -388,6 +464,23 @@ func (p SessionsPane) normalListView() string {
 		Render(strings.Join(sessionListItems, "\n"))
 }
 
+// HelpKeys returns the bindings of the current operation, renaming and deleting only wait for a confirmation
+func (p SessionsPane) HelpKeys() []key.Binding {
+	if p.operationMode != defaultMode {
+		return []key.Binding{p.keyMap.apply, p.keyMap.cancel}
+	}
+
+	return []key.Binding{
+		listNavigationKeys.up,
+		listNavigationKeys.down,
+		p.keyMap.apply,
+		p.keyMap.addNew,
+		p.keyMap.rename,
+		p.keyMap.delete,
+		p.keyMap.contextStrategy,
+	}
+}
+
 func (p SessionsPane) AllowFocusChange() bool {
 	return p.operationMode == defaultMode
 }
@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -24,13 +27,44 @@ const (
 	modelMode = iota
 	maxTokensMode
 	frequencyMode
+	reasoningEffortMode
+	themeMode
+	temperatureMode
 )
 
-const (
-	ModelPickerKey = "m"
-	FrequencyKey   = "f"
-	MaxTokensKey   = "t"
-)
+const maxTemperature = 2.0
+
+type settingsKeyMap struct {
+	modelPicker     key.Binding
+	frequency       key.Binding
+	maxTokens       key.Binding
+	reasoningEffort key.Binding
+	temperature     key.Binding
+	colorScheme     key.Binding
+}
+
+var defaultSettingsKeyMap = settingsKeyMap{
+	modelPicker:     key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "pick model")),
+	frequency:       key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "set frequency")),
+	maxTokens:       key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "set max tokens")),
+	reasoningEffort: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "set reasoning effort")),
+	temperature:     key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "set temperature")),
+	colorScheme:     key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "pick theme")),
+}
+
+func init() {
+	util.RegisterKeyBindings(util.SettingsKeys, util.KeyBindings{
+		"modelPicker":     &defaultSettingsKeyMap.modelPicker,
+		"frequency":       &defaultSettingsKeyMap.frequency,
+		"maxTokens":       &defaultSettingsKeyMap.maxTokens,
+		"reasoningEffort": &defaultSettingsKeyMap.reasoningEffort,
+		"temperature":     &defaultSettingsKeyMap.temperature,
+		"colorScheme":     &defaultSettingsKeyMap.colorScheme,
+	})
+}
+
+// `none` resets the setting, so that the param is not sent at all
+var reasoningEffortValues = []string{"none", "low", "medium", "high"}
 
 type SettingsPane struct {
 	terminalWidth   int
@@ -43,7 +77,9 @@ type SettingsPane struct {
 	loading         bool
 	colors          util.SchemeColors
 
+	keys        settingsKeyMap
 	modelPicker components.ModelsList
+	themePicker components.ModelsList
 
 	container lipgloss.Style
 
@@ -90,31 +126,69 @@ func NewSettingsPane(db *sql.DB, ctx context.Context) SettingsPane {
 	}
 
 	settingsService = settings.NewSettingsService(db)
-	openAiClient := clients.NewOpenAiClient(config.ChatGPTApiUrl, config.SystemMessage)
+	openAiClient := clients.NewOpenAiClient(*config)
 
-	colors := config.ColorScheme.GetColors()
-	listItemSpan = listItemSpan.Copy().Foreground(colors.DefaultTextColor)
-	listItemHeading = listItemHeading.Copy().Foreground(colors.MainColor)
-	settingsListHeader = settingsListHeader.Copy().Foreground(colors.DefaultTextColor)
-	spinnerStyle = spinnerStyle.Copy().Foreground(colors.AccentColor)
 	containerStyle := lipgloss.NewStyle().
-		Border(lipgloss.ThickBorder(), true).
-		BorderForeground(colors.NormalTabBorderColor)
-
-	spinner := initSpinner()
+		Border(lipgloss.ThickBorder(), true)
 
-	return SettingsPane{
-		colors:          colors,
+	pane := SettingsPane{
 		terminalWidth:   util.DefaultTerminalWidth,
 		mode:            viewMode,
+		keys:            defaultSettingsKeyMap,
 		container:       containerStyle,
 		config:          config,
 		openAiClient:    openAiClient,
 		settingsService: settingsService,
-		spinner:         spinner,
+		spinner:         initSpinner(),
 		initMode:        true,
 		loading:         true,
 	}
+	pane.applyColors(config.ColorScheme.GetColors())
+	return pane
+}
+
+func (p *SettingsPane) applyColors(colors util.SchemeColors) {
+	p.colors = colors
+	listItemSpan = listItemSpan.Copy().Foreground(colors.DefaultTextColor)
+	listItemHeading = listItemHeading.Copy().Foreground(colors.MainColor)
+	settingsListHeader = settingsListHeader.Copy().Foreground(colors.DefaultTextColor)
+	spinnerStyle = spinnerStyle.Copy().Foreground(colors.AccentColor)
+	p.spinner.Style = spinnerStyle
+
+	borderColor := colors.NormalTabBorderColor
+	if p.isFocused {
+		borderColor = colors.ActiveTabBorderColor
+	}
+	p.container = p.container.Copy().BorderForeground(borderColor)
+}
+
+// applyConfig reloads the settings when the provider changes, since its models differ.
+// A new default model is selected right away
+func (p *SettingsPane) applyConfig(cfg config.Config) tea.Cmd {
+	previous := *p.config
+	p.config = &cfg
+	p.openAiClient = clients.NewOpenAiClient(cfg)
+	p.applyColors(cfg.ColorScheme.GetColors())
+
+	switch {
+	case cfg.ChatGPTApiUrl != previous.ChatGPTApiUrl:
+		p.loading = true
+		p.mode = viewMode
+		return tea.Batch(
+			func() tea.Msg { return p.settingsService.GetSettings(nil, cfg) },
+			p.spinner.Tick)
+
+	case cfg.DefaultModel != previous.DefaultModel && cfg.DefaultModel != "":
+		p.settings.Model = cfg.DefaultModel
+		newSettings, err := p.settingsService.UpdateSettings(p.settings)
+		if err != nil {
+			return util.MakeErrorMsg(err.Error())
+		}
+		p.settings = newSettings
+		return settings.MakeSettingsUpdateMsg(p.settings, nil)
+	}
+
+	return nil
 }
 
 func (p *SettingsPane) Init() tea.Cmd {
@@ -154,17 +228,39 @@ func (p SettingsPane) Update(msg tea.Msg) (SettingsPane, tea.Cmd) {
 		p.loading = false
 		p.mode = viewMode
 
+	case config.ConfigChanged:
+		cmds = append(cmds, p.applyConfig(msg.Config))
+
 	case settings.UpdateSettingsEvent:
+		// settings are reloaded after the provider changed in the config
+		p.settings = msg.Settings
+		p.loading = false
 		if p.initMode {
-			p.settings = msg.Settings
 			w, h := util.CalcModelsListSize(p.terminalWidth, p.terminalHeight)
 			p.modelPicker = components.NewModelsList([]list.Item{components.ModelsListItem(msg.Settings.Model)}, w, h, p.colors)
 			p.initMode = false
-			p.loading = false
 
 			cmds = append(cmds, util.SendAsyncDependencyReadyMsg(util.SettingsPaneModule))
 		}
 
+	case util.PickModelMsg:
+		if !p.initMode {
+			p.mode = viewMode
+			cmds = append(cmds, p.openModelPicker())
+		}
+
+	case settings.ModelChangeRequested:
+		cmds = append(cmds, p.changeModel(msg.Model))
+
+	case settings.TemperatureChangeRequested:
+		p.settings.Temperature = msg.Temperature
+		cmds = append(cmds, p.saveSettings())
+
+	case util.PickThemeMsg:
+		if !p.initMode {
+			p.openThemePicker()
+		}
+
 	case util.ModelsLoaded:
 		p.loading = false
 		p.mode = modelMode
@@ -182,6 +278,9 @@ func (p SettingsPane) Update(msg tea.Msg) (SettingsPane, tea.Cmd) {
 			} else if p.mode == modelMode {
 				cmd = p.handleModelMode(msg)
 				cmds = append(cmds, cmd)
+			} else if p.mode == themeMode {
+				cmd = p.handleThemeMode(msg)
+				cmds = append(cmds, cmd)
 			} else {
 				cmd = p.handleEditMode(msg)
 				cmds = append(cmds, cmd)
@@ -194,20 +293,29 @@ func (p SettingsPane) Update(msg tea.Msg) (SettingsPane, tea.Cmd) {
 
 func (p SettingsPane) View() string {
 	editForm := ""
-	if p.mode == modelMode {
+	if p.mode == modelMode || p.mode == themeMode {
+		picker := p.modelPicker
+		if p.mode == themeMode {
+			picker = p.themePicker
+		}
 		return p.container.Render(
 			lipgloss.JoinVertical(lipgloss.Left,
 				settingsListHeader.Render("Settings"),
-				p.modelPicker.View(),
+				picker.View(),
 			),
 		)
 	}
 
-	if p.mode != viewMode && p.mode != modelMode {
+	if p.mode != viewMode {
 		editForm = p.textInput.View()
 	}
 
-	modelRowContent := p.listItemRenderer("model", p.settings.Model)
+	modelLabel := p.settings.Model
+	if model := p.config.Azure.GetModelName(p.settings.Model); model != p.settings.Model {
+		modelLabel = fmt.Sprintf("%s (%s)", p.settings.Model, model)
+	}
+
+	modelRowContent := p.listItemRenderer("model", modelLabel)
 	if p.loading {
 		modelRowContent = p.listItemRenderer(p.spinner.View(), "")
 	}
@@ -221,6 +329,9 @@ func (p SettingsPane) View() string {
 					modelRowContent,
 					p.listItemRenderer("frequency", fmt.Sprint(p.settings.Frequency)),
 					p.listItemRenderer("max_tokens", fmt.Sprint((p.settings.MaxTokens))),
+					p.listItemRenderer("reasoning_effort", reasoningEffortLabel(p.settings.ReasoningEffort)),
+					p.listItemRenderer("temperature", temperatureLabel(p.settings.Temperature)),
+					p.listItemRenderer("theme", themeLabel(p.config.ColorScheme)),
 				),
 			),
 			editForm,
@@ -228,6 +339,30 @@ func (p SettingsPane) View() string {
 	)
 }
 
+// HelpKeys returns the bindings of the settings list, or of the picker or input that is open
+func (p SettingsPane) HelpKeys() []key.Binding {
+	switch p.mode {
+	case viewMode:
+		return []key.Binding{
+			p.keys.modelPicker,
+			p.keys.frequency,
+			p.keys.maxTokens,
+			p.keys.reasoningEffort,
+			p.keys.temperature,
+			p.keys.colorScheme,
+		}
+	case modelMode, themeMode:
+		return []key.Binding{
+			listNavigationKeys.up,
+			listNavigationKeys.down,
+			listNavigationKeys.choose,
+			listNavigationKeys.cancel,
+		}
+	}
+
+	return []key.Binding{listNavigationKeys.apply, listNavigationKeys.cancel}
+}
+
 func (p SettingsPane) AllowFocusChange() bool {
 	return p.mode == viewMode
 }
@@ -268,42 +403,48 @@ func (p *SettingsPane) handleModelMode(msg tea.KeyMsg) tea.Cmd {
 
 func (p *SettingsPane) handleViewMode(msg tea.KeyMsg) tea.Cmd {
 	var cmd tea.Cmd
-	switch msg.Type {
-	case tea.KeyRunes:
-		key := string(msg.Runes)
-
-		if key == ModelPickerKey || key == FrequencyKey || key == MaxTokensKey {
-			ti := textinput.New()
-			ti.PromptStyle = lipgloss.NewStyle().PaddingLeft(util.DefaultElementsPadding)
-			p.textInput = ti
-
-			switch key {
-			case ModelPickerKey:
-				p.loading = true
-				return tea.Batch(
-					func() tea.Msg { return p.loadModels(p.config.ChatGPTApiUrl) },
-					p.spinner.Tick)
-
-			case FrequencyKey:
-				p.mode = frequencyMode
-				p.textInput.Placeholder = "Enter Frequency Number"
-				p.textInput.Validate = func(str string) error {
-					if _, err := strconv.ParseFloat(str, 64); err == nil {
-						log.Printf("'%s' is a floating-point number.\n", str)
-					} else {
-						log.Printf("'%s' is not a floating-point number.\n", str)
-						return err
-					}
-
-					return nil
+
+	if key.Matches(msg, p.keys.colorScheme) {
+		p.openThemePicker()
+		return nil
+	}
+
+	if key.Matches(msg, p.keys.modelPicker, p.keys.frequency, p.keys.maxTokens, p.keys.reasoningEffort, p.keys.temperature) {
+		ti := textinput.New()
+		ti.PromptStyle = lipgloss.NewStyle().PaddingLeft(util.DefaultElementsPadding)
+		p.textInput = ti
+
+		switch {
+		case key.Matches(msg, p.keys.modelPicker):
+			return p.openModelPicker()
+
+		case key.Matches(msg, p.keys.frequency):
+			p.mode = frequencyMode
+			p.textInput.Placeholder = "Enter Frequency Number"
+			p.textInput.Validate = func(str string) error {
+				if _, err := strconv.ParseFloat(str, 64); err == nil {
+					log.Printf("'%s' is a floating-point number.\n", str)
+				} else {
+					log.Printf("'%s' is not a floating-point number.\n", str)
+					return err
 				}
-			case MaxTokensKey:
-				p.textInput.Placeholder = "Enter Max Tokens"
-				p.mode = maxTokensMode
+
+				return nil
 			}
+		case key.Matches(msg, p.keys.maxTokens):
+			p.textInput.Placeholder = "Enter Max Tokens"
+			p.mode = maxTokensMode
+
+		case key.Matches(msg, p.keys.reasoningEffort):
+			p.textInput.Placeholder = strings.Join(reasoningEffortValues, "/")
+			p.mode = reasoningEffortMode
 
-			p.textInput.Focus()
+		case key.Matches(msg, p.keys.temperature):
+			p.textInput.Placeholder = fmt.Sprintf("0-%g or none", maxTemperature)
+			p.mode = temperatureMode
 		}
+
+		p.textInput.Focus()
 	}
 
 	return cmd
@@ -339,6 +480,23 @@ func (p *SettingsPane) handleEditMode(msg tea.KeyMsg) tea.Cmd {
 				cmd = util.MakeErrorMsg("Invalid Tokens")
 			}
 			p.settings.MaxTokens = newTokens
+
+		case reasoningEffortMode:
+			effort := strings.ToLower(strings.TrimSpace(inputValue))
+			if !slices.Contains(reasoningEffortValues, effort) {
+				return util.MakeErrorMsg("Invalid reasoning effort, use one of: " + strings.Join(reasoningEffortValues, ", "))
+			}
+			if effort == "none" {
+				effort = ""
+			}
+			p.settings.ReasoningEffort = effort
+
+		case temperatureMode:
+			temperature, err := parseTemperature(inputValue)
+			if err != nil {
+				return util.MakeErrorMsg(err.Error())
+			}
+			p.settings.Temperature = temperature
 		}
 
 		newSettings, err := settingsService.UpdateSettings(p.settings)
@@ -354,8 +512,143 @@ func (p *SettingsPane) handleEditMode(msg tea.KeyMsg) tea.Cmd {
 	return cmd
 }
 
-func (p SettingsPane) loadModels(apiUrl string) tea.Msg {
-	availableModels, err := p.settingsService.GetProviderModels(apiUrl)
+// openModelPicker loads the models of the provider, the picker opens once they arrive
+func (p *SettingsPane) openModelPicker() tea.Cmd {
+	p.loading = true
+	return tea.Batch(
+		func() tea.Msg { return p.loadModels(*p.config) },
+		p.spinner.Tick)
+}
+
+// openThemePicker rescans the themes folder, so that new theme files show up without a restart
+func (p *SettingsPane) openThemePicker() {
+	util.LoadThemes()
+
+	items := []list.Item{}
+	selected := 0
+	for i, scheme := range util.GetColorSchemes() {
+		items = append(items, components.ModelsListItem(scheme))
+		if scheme == p.config.ColorScheme {
+			selected = i
+		}
+	}
+
+	w, h := util.CalcModelsListSize(p.terminalWidth, p.terminalHeight)
+	p.themePicker = components.NewModelsList(items, w, h, p.colors)
+	p.themePicker.SetItemName("theme", "themes")
+	p.themePicker.Select(selected)
+	p.mode = themeMode
+}
+
+func (p *SettingsPane) handleThemeMode(msg tea.KeyMsg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		p.mode = viewMode
+		return nil
+
+	case tea.KeyEnter:
+		i, ok := p.themePicker.GetSelectedItem()
+		if !ok {
+			return nil
+		}
+		p.mode = viewMode
+		return p.saveColorScheme(util.ColorScheme(i))
+	}
+
+	p.themePicker, cmd = p.themePicker.Update(msg)
+	return cmd
+}
+
+// saveColorScheme writes the scheme into the config file and applies it right away,
+// the config watcher picks up the same change afterwards
+func (p SettingsPane) saveColorScheme(scheme util.ColorScheme) tea.Cmd {
+	cfg := *p.config
+	cfg.ColorScheme = scheme
+
+	return func() tea.Msg {
+		path, err := config.GetConfigPath()
+		if err == nil {
+			err = config.SaveColorScheme(path, scheme)
+		}
+		if err != nil {
+			return util.ErrorEvent{Message: "Failed to save the theme: " + err.Error()}
+		}
+		return config.ConfigChanged{Config: cfg}
+	}
+}
+
+func (p *SettingsPane) saveSettings() tea.Cmd {
+	newSettings, err := p.settingsService.UpdateSettings(p.settings)
+	if err != nil {
+		return util.MakeErrorMsg(err.Error())
+	}
+
+	p.settings = newSettings
+	return settings.MakeSettingsUpdateMsg(p.settings, nil)
+}
+
+// changeModel only accepts models of the provider, the list is usually cached
+func (p SettingsPane) changeModel(model string) tea.Cmd {
+	cfg := *p.config
+	newSettings := p.settings
+	newSettings.Model = model
+
+	return func() tea.Msg {
+		models, err := p.settingsService.GetProviderModels(cfg)
+		if err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+		if !slices.Contains(models, model) {
+			return util.ErrorEvent{Message: fmt.Sprintf("Model %s is not available", model)}
+		}
+
+		newSettings, err = p.settingsService.UpdateSettings(newSettings)
+		if err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+		return settings.UpdateSettingsEvent{Settings: newSettings}
+	}
+}
+
+// parseTemperature returns nil for `none`, so that the provider default is used
+func parseTemperature(value string) (*float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "none" {
+		return nil, nil
+	}
+
+	temperature, err := strconv.ParseFloat(value, 64)
+	if err != nil || temperature < 0 || temperature > maxTemperature {
+		return nil, fmt.Errorf("Invalid temperature, use a number from 0 to %g or none", maxTemperature)
+	}
+	return &temperature, nil
+}
+
+func temperatureLabel(temperature *float64) string {
+	if temperature == nil {
+		return "default"
+	}
+	return strconv.FormatFloat(*temperature, 'f', -1, 64)
+}
+
+func themeLabel(scheme util.ColorScheme) string {
+	if scheme == "" {
+		return string(util.OriginalPink)
+	}
+	return string(scheme)
+}
+
+func reasoningEffortLabel(effort string) string {
+	if effort == "" {
+		return "none"
+	}
+	return effort
+}
+
+func (p SettingsPane) loadModels(cfg config.Config) tea.Msg {
+	availableModels, err := p.settingsService.GetProviderModels(cfg)
 
 	if err != nil {
 		return util.ErrorEvent{Message: err.Error()}
@@ -0,0 +1,220 @@
+package panes
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tearingItUp786/nekot/sessions"
+	"github.com/tearingItUp786/nekot/settings"
+	"github.com/tearingItUp786/nekot/snippets"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const slashCommandPrefix = "/"
+
+// slashCommand is run from the prompt instead of being sent to the model.
+// Values are the known arguments, they are offered as completions
+type slashCommand struct {
+	name        string
+	args        string
+	description string
+	values      []string
+	run         func(args string) (tea.Cmd, error)
+}
+
+// SlashSuggestion describes a command that matches the typed prompt
+type SlashSuggestion struct {
+	Usage       string
+	Description string
+}
+
+var slashCommands = []slashCommand{
+	{
+		name:        "model",
+		args:        "<model>",
+		description: "switch the model",
+		run: func(args string) (tea.Cmd, error) {
+			if args == "" {
+				return nil, errors.New("Missing model name")
+			}
+			return settings.SendModelChangeRequestedMsg(args), nil
+		},
+	},
+	{
+		name:        "new",
+		description: "start a new session",
+		run: func(args string) (tea.Cmd, error) {
+			return util.SendNewSessionMsg, nil
+		},
+	},
+	{
+		name:        "rename",
+		args:        "<name>",
+		description: "rename the current session",
+		run: func(args string) (tea.Cmd, error) {
+			if args == "" {
+				return nil, errors.New("Missing session name")
+			}
+			return sessions.SendRenameSessionRequestedMsg(args), nil
+		},
+	},
+	{
+		name:        "system",
+		args:        "[message]",
+		description: "set the system message of the session, without a message the config one is used",
+		run: func(args string) (tea.Cmd, error) {
+			return sessions.SendSystemMessageChangeRequestedMsg(args), nil
+		},
+	},
+	{
+		name:        "temp",
+		args:        "<0-2|none>",
+		description: "set the temperature",
+		values:      []string{"none"},
+		run: func(args string) (tea.Cmd, error) {
+			temperature, err := parseTemperature(args)
+			if err != nil {
+				return nil, err
+			}
+			return settings.SendTemperatureChangeRequestedMsg(temperature), nil
+		},
+	},
+	{
+		name:        "export",
+		args:        "<md|json>",
+		description: "export the session into the working directory",
+		values:      []string{string(sessions.MarkdownExport), string(sessions.JsonExport)},
+		run: func(args string) (tea.Cmd, error) {
+			format := sessions.ExportFormat(strings.ToLower(args))
+			if !slices.Contains(sessions.ExportFormats, format) {
+				return nil, errors.New("Unknown export format, use md or json")
+			}
+			return sessions.SendExportSessionRequestedMsg(format), nil
+		},
+	},
+	{
+		name:        "snippet",
+		args:        "[name] | save <name> <text> | delete <name>",
+		description: "insert a snippet, save the text as one or delete it",
+		run:         runSnippetCommand,
+	},
+	{
+		name:        "clear",
+		description: "remove all messages of the session",
+		run: func(args string) (tea.Cmd, error) {
+			return sessions.SendClearSessionRequestedMsg, nil
+		},
+	},
+}
+
+// runSnippetCommand picks the snippet from a list when no name is given.
+// The text of a saved snippet may span several lines of the editor
+func runSnippetCommand(args string) (tea.Cmd, error) {
+	action, rest := splitFirstWord(args)
+
+	switch action {
+	case "save":
+		name, content := splitFirstWord(rest)
+		if name == "" {
+			return nil, errors.New("Missing snippet name")
+		}
+		if content == "" {
+			return nil, errors.New("Missing snippet text")
+		}
+		return snippets.SendSaveSnippetRequestedMsg(name, content), nil
+
+	case "delete":
+		if rest == "" {
+			return nil, errors.New("Missing snippet name")
+		}
+		return snippets.SendDeleteSnippetRequestedMsg(rest), nil
+	}
+
+	return snippets.SendInsertSnippetRequestedMsg(args), nil
+}
+
+// isSlashCommand is false for prompts starting with `//`, they are sent with a single slash
+func isSlashCommand(prompt string) bool {
+	return strings.HasPrefix(prompt, slashCommandPrefix) && !strings.HasPrefix(prompt, slashCommandPrefix+slashCommandPrefix)
+}
+
+func unescapeSlash(prompt string) string {
+	if strings.HasPrefix(prompt, slashCommandPrefix+slashCommandPrefix) {
+		return strings.TrimPrefix(prompt, slashCommandPrefix)
+	}
+	return prompt
+}
+
+// splitSlashCommand cuts at the first space or line break, multiline arguments are kept as they are
+func splitSlashCommand(prompt string) (name, args string) {
+	name, args = splitFirstWord(strings.TrimPrefix(strings.TrimSpace(prompt), slashCommandPrefix))
+	return strings.ToLower(name), args
+}
+
+func splitFirstWord(text string) (word, rest string) {
+	text = strings.TrimSpace(text)
+	wordEnd := strings.IndexFunc(text, unicode.IsSpace)
+	if wordEnd < 0 {
+		return text, ""
+	}
+	return text[:wordEnd], strings.TrimSpace(text[wordEnd:])
+}
+
+func runSlashCommand(prompt string) tea.Cmd {
+	name, args := splitSlashCommand(prompt)
+	idx := slices.IndexFunc(slashCommands, func(c slashCommand) bool { return c.name == name })
+	if idx < 0 {
+		return util.MakeErrorMsg(fmt.Sprintf("Unknown command /%s, type / to see the available commands", name))
+	}
+
+	cmd, err := slashCommands[idx].run(args)
+	if err != nil {
+		return util.MakeErrorMsg(fmt.Sprintf("/%s: %s", name, err))
+	}
+	return cmd
+}
+
+// getSlashCompletions lists the commands and their known arguments for the completion of the input
+func getSlashCompletions() []string {
+	completions := []string{}
+	for _, command := range slashCommands {
+		completion := slashCommandPrefix + command.name
+		if command.args != "" && len(command.values) == 0 {
+			completion += " "
+		}
+		completions = append(completions, completion)
+
+		for _, value := range command.values {
+			completions = append(completions, fmt.Sprintf("%s%s %s", slashCommandPrefix, command.name, value))
+		}
+	}
+	return completions
+}
+
+// getSlashSuggestions returns the commands starting with the typed name, or the typed command once it has arguments
+func getSlashSuggestions(prompt string) []SlashSuggestion {
+	if !isSlashCommand(prompt) || strings.Contains(prompt, "\n") {
+		return nil
+	}
+
+	name, _ := splitSlashCommand(prompt)
+	hasArgs := strings.Contains(strings.TrimLeft(prompt, slashCommandPrefix), " ")
+
+	suggestions := []SlashSuggestion{}
+	for _, command := range slashCommands {
+		if hasArgs && command.name != name || !strings.HasPrefix(command.name, name) {
+			continue
+		}
+
+		usage := slashCommandPrefix + command.name
+		if command.args != "" {
+			usage += " " + command.args
+		}
+		suggestions = append(suggestions, SlashSuggestion{Usage: usage, Description: command.description})
+	}
+	return suggestions
+}
@@ -0,0 +1,301 @@
+package panes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tearingItUp786/nekot/components"
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/usage"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	maxChartDays        = 30
+	topEntriesAmount    = 5
+	usageLabelWidth     = 24
+	usageTextsWidth     = 42
+	usagePaneHorPadding = 2
+)
+
+type usageStats struct {
+	dailyUsage     []usage.DailyUsage
+	models         []usage.ReportRow
+	sessions       []usage.ReportRow
+	monthlyCost    float64
+	averageLatency time.Duration
+}
+
+type usageStatsLoaded struct {
+	stats usageStats
+	err   error
+}
+
+// UsagePane is a dashboard with statistics of the recorded requests.
+// It takes the place of the chat pane while focused
+type UsagePane struct {
+	usageService *usage.UsageService
+	stats        usageStats
+	err          error
+	isFocused    bool
+	viewMode     util.ViewMode
+
+	terminalWidth  int
+	terminalHeight int
+
+	colors     util.SchemeColors
+	container  lipgloss.Style
+	titleStyle lipgloss.Style
+	mutedStyle lipgloss.Style
+	chartStyle lipgloss.Style
+}
+
+func NewUsagePane(db *sql.DB, ctx context.Context) UsagePane {
+	config, ok := config.FromContext(ctx)
+	if !ok {
+		fmt.Println("No config found")
+		panic("No config found in context")
+	}
+
+	w, h := util.CalcChatPaneSize(util.DefaultTerminalWidth, util.DefaultTerminalHeight, util.NormalMode)
+	container := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		MarginRight(util.ChatPaneMarginRight).
+		PaddingLeft(1).
+		Width(w).
+		Height(h)
+
+	pane := UsagePane{
+		usageService:   usage.NewUsageService(db),
+		viewMode:       util.NormalMode,
+		terminalWidth:  util.DefaultTerminalWidth,
+		terminalHeight: util.DefaultTerminalHeight,
+		container:      container,
+	}
+	pane.applyColors(config.ColorScheme.GetColors())
+	return pane
+}
+
+func (p *UsagePane) applyColors(colors util.SchemeColors) {
+	p.colors = colors
+	p.container = p.container.Copy().BorderForeground(colors.ActiveTabBorderColor)
+	p.titleStyle = lipgloss.NewStyle().Bold(true).Foreground(colors.AccentColor)
+	p.mutedStyle = lipgloss.NewStyle().Foreground(colors.NormalTabBorderColor)
+	p.chartStyle = lipgloss.NewStyle().Foreground(colors.HighlightColor)
+}
+
+func (p UsagePane) Init() tea.Cmd {
+	return nil
+}
+
+func (p UsagePane) Update(msg tea.Msg) (UsagePane, tea.Cmd) {
+	switch msg := msg.(type) {
+	case util.FocusEvent:
+		p.isFocused = msg.IsFocused
+		if p.isFocused {
+			return p, p.loadStats()
+		}
+
+	case usageStatsLoaded:
+		p.stats = msg.stats
+		p.err = msg.err
+
+	case util.CopyUsageReportMsg:
+		return p, p.copyReport()
+
+	case config.ConfigChanged:
+		p.applyColors(msg.Config.ColorScheme.GetColors())
+
+	case util.ViewModeChanged:
+		p.viewMode = msg.Mode
+		p.resize()
+
+	case tea.WindowSizeMsg:
+		p.terminalWidth = msg.Width
+		p.terminalHeight = msg.Height
+		p.resize()
+	}
+
+	return p, nil
+}
+
+func (p *UsagePane) resize() {
+	w, h := util.CalcChatPaneSize(p.terminalWidth, p.terminalHeight, p.viewMode)
+	p.container = p.container.Width(w).Height(h)
+}
+
+func (p UsagePane) loadStats() tea.Cmd {
+	service := p.usageService
+	w, _ := util.CalcChatPaneSize(p.terminalWidth, p.terminalHeight, p.viewMode)
+	days := max(1, min(maxChartDays, w-usagePaneHorPadding))
+
+	return func() tea.Msg {
+		stats := usageStats{}
+		var err error
+
+		if stats.dailyUsage, err = service.GetDailyUsage(days); err != nil {
+			return usageStatsLoaded{err: err}
+		}
+		if stats.models, err = service.GetReport(usage.ByModel); err != nil {
+			return usageStatsLoaded{err: err}
+		}
+		if stats.sessions, err = service.GetReport(usage.BySession); err != nil {
+			return usageStatsLoaded{err: err}
+		}
+		if stats.monthlyCost, err = service.GetMonthlyCost(); err != nil {
+			return usageStatsLoaded{err: err}
+		}
+		if stats.averageLatency, err = service.GetAverageLatency(); err != nil {
+			return usageStatsLoaded{err: err}
+		}
+
+		return usageStatsLoaded{stats: stats}
+	}
+}
+
+// copyReport copies the full tables, the dashboard only shows the top entries
+func (p UsagePane) copyReport() tea.Cmd {
+	service := p.usageService
+	return func() tea.Msg {
+		report := strings.Builder{}
+		if err := service.WriteReport(&report); err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+		if err := clipboard.WriteAll(report.String()); err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+		return util.NotificationMsg{Notification: util.CopiedNotification}
+	}
+}
+
+func (p UsagePane) View() string {
+	w, h := util.CalcChatPaneSize(p.terminalWidth, p.terminalHeight, p.viewMode)
+	contentWidth := max(0, w-usagePaneHorPadding)
+
+	var content string
+	switch {
+	case p.err != nil:
+		content = util.RenderErrorMessage(p.err.Error(), contentWidth, p.colors)
+	case len(p.stats.models) == 0:
+		content = p.titleStyle.Render("Usage statistics") + "\n\n" +
+			p.mutedStyle.Render("No requests recorded yet")
+	default:
+		content = lipgloss.JoinVertical(
+			lipgloss.Left,
+			p.titleStyle.Render("Usage statistics"),
+			p.summaryView(),
+			"",
+			p.dailyChartsView(),
+			"",
+			p.titleStyle.Render("Top models"),
+			p.rankingView(p.stats.models, contentWidth, true),
+			"",
+			p.titleStyle.Render("Longest sessions"),
+			p.rankingView(p.stats.sessions, contentWidth, false),
+		)
+	}
+
+	return p.container.Render(
+		lipgloss.NewStyle().MaxWidth(contentWidth).MaxHeight(h).Render(content),
+	)
+}
+
+func (p UsagePane) summaryView() string {
+	requests := 0
+	tokens := 0
+	for _, day := range p.stats.dailyUsage {
+		requests += day.Requests
+		tokens += day.Tokens
+	}
+
+	return fmt.Sprintf(
+		"This month: %s   Requests (%dd): %d   Tokens (%dd): %s   Avg latency: %s",
+		usage.FormatCost(p.stats.monthlyCost),
+		len(p.stats.dailyUsage),
+		requests,
+		len(p.stats.dailyUsage),
+		formatTokensAmount(tokens),
+		formatLatency(p.stats.averageLatency))
+}
+
+func (p UsagePane) dailyChartsView() string {
+	tokens := []int{}
+	requests := []int{}
+	maxTokens := 0
+	maxRequests := 0
+	for _, day := range p.stats.dailyUsage {
+		tokens = append(tokens, day.Tokens)
+		requests = append(requests, day.Requests)
+		maxTokens = max(maxTokens, day.Tokens)
+		maxRequests = max(maxRequests, day.Requests)
+	}
+
+	firstDay := p.stats.dailyUsage[0].Day.Format("Jan 02")
+	axis := p.mutedStyle.Render(firstDay + strings.Repeat(" ", max(1, len(tokens)-len(firstDay)-len("today"))) + "today")
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		fmt.Sprintf("Tokens per day %s", p.mutedStyle.Render(fmt.Sprintf("(max %s)", formatTokensAmount(maxTokens)))),
+		components.Sparkline(tokens, p.chartStyle),
+		axis,
+		"",
+		fmt.Sprintf("Requests per day %s", p.mutedStyle.Render(fmt.Sprintf("(max %d)", maxRequests))),
+		components.Sparkline(requests, p.chartStyle),
+		axis,
+	)
+}
+
+// rankingView renders bars of the entries with the most tokens
+func (p UsagePane) rankingView(rows []usage.ReportRow, width int, showLatency bool) string {
+	rows = slices.Clone(rows)
+	slices.SortFunc(rows, func(a, b usage.ReportRow) int {
+		return (b.PromptTokens + b.CompletionTokens) - (a.PromptTokens + a.CompletionTokens)
+	})
+	rows = rows[:min(topEntriesAmount, len(rows))]
+
+	maxTokens := 0
+	for _, row := range rows {
+		maxTokens = max(maxTokens, row.PromptTokens+row.CompletionTokens)
+	}
+
+	barWidth := max(0, width-usageLabelWidth-usageTextsWidth)
+	lines := []string{}
+	for _, row := range rows {
+		rowTokens := row.PromptTokens + row.CompletionTokens
+		details := fmt.Sprintf("%s tok, %d req, %s", formatTokensAmount(rowTokens), row.Requests, usage.FormatCost(row.Cost))
+		if showLatency {
+			details += ", " + formatLatency(row.AverageLatency)
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"%-*s %s %s",
+			usageLabelWidth-1,
+			truncateLabel(row.Key, usageLabelWidth-1),
+			components.Bar(rowTokens, maxTokens, barWidth, p.chartStyle, p.mutedStyle),
+			details))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func truncateLabel(label string, width int) string {
+	runes := []rune(label)
+	if len(runes) <= width {
+		return label
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+func formatLatency(latency time.Duration) string {
+	if latency == 0 {
+		return "n/a"
+	}
+	return latency.Round(time.Millisecond * 100).String()
+}
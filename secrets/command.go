@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const keyCommandTimeout = time.Second * 30
+
+// runKeyCommand runs the command in a shell and returns its output, e.g. `pass show openai`.
+// Password managers may ask for a pin, so the timeout is generous
+func runKeyCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if details := strings.TrimSpace(stderr.String()); details != "" {
+			return "", fmt.Errorf("apiKeyCommand `%s` failed: %w: %s", command, err, details)
+		}
+		return "", fmt.Errorf("apiKeyCommand `%s` failed: %w", command, err)
+	}
+
+	// only the first line is used, `pass` keeps metadata on the following ones
+	key, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(key), nil
+}
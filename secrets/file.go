@@ -0,0 +1,150 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tearingItUp786/nekot/util"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	keysFileName     = "keys.enc"
+	kdfIterations    = 600_000
+	kdfSaltLength    = 16
+	encryptionKeyLen = 32
+)
+
+// every key is encrypted with AES-GCM using its own salt, so profiles can be added without re-encrypting the others
+type encryptedKey struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+type keysFile struct {
+	Profiles map[string]encryptedKey `json:"profiles"`
+}
+
+func getKeysFilePath() (string, error) {
+	appPath, err := util.GetAppDataPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appPath, keysFileName), nil
+}
+
+func loadKeysFile() (keysFile, error) {
+	file := keysFile{Profiles: map[string]encryptedKey{}}
+
+	path, err := getKeysFilePath()
+	if err != nil {
+		return file, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("failed to read the keys file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &file); err != nil {
+		return file, fmt.Errorf("keys file %s is corrupted: %w", path, err)
+	}
+	if file.Profiles == nil {
+		file.Profiles = map[string]encryptedKey{}
+	}
+	return file, nil
+}
+
+func readFromFile(profile, passphrase string) (string, error) {
+	file, err := loadKeysFile()
+	if err != nil {
+		return "", err
+	}
+
+	entry, ok := file.Profiles[profile]
+	if !ok {
+		return "", ErrNoApiKey
+	}
+	if passphrase == "" {
+		return "", ErrPassphraseRequired
+	}
+
+	aead, err := newCipher(passphrase, entry.Salt)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := aead.Open(nil, entry.Nonce, entry.Data, []byte(profile))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the api key, check the passphrase")
+	}
+	return string(key), nil
+}
+
+func writeToFile(profile, key, passphrase string) error {
+	if passphrase == "" {
+		return ErrPassphraseRequired
+	}
+
+	file, err := loadKeysFile()
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, kdfSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := newCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	// the profile is authenticated as well, so that entries can't be swapped
+	file.Profiles[profile] = encryptedKey{
+		Salt:  salt,
+		Nonce: nonce,
+		Data:  aead.Seal(nil, nonce, []byte(key), []byte(profile)),
+	}
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path, err := getKeysFilePath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey([]byte(passphrase), salt))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey is PBKDF2 with HMAC-SHA256
+func deriveKey(passphrase, salt []byte) []byte {
+	return pbkdf2.Key(passphrase, salt, kdfIterations, encryptionKeyLen, sha256.New)
+}
@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The Secret Service is accessed through `secret-tool` of libsecret,
+// which ships with GNOME keyring and KWallet based desktops
+const (
+	secretTool       = "secret-tool"
+	keyringService   = "nekot"
+	keyringAttribute = "profile"
+)
+
+func isKeyringAvailable() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := exec.LookPath(secretTool)
+	return err == nil
+}
+
+func readFromKeyring(profile string) (string, error) {
+	if !isKeyringAvailable() {
+		return "", fmt.Errorf("keyring store needs `%s` of libsecret, which is only available on Linux", secretTool)
+	}
+
+	output, err := exec.Command(secretTool, "lookup", "service", keyringService, keyringAttribute, profile).Output()
+	// lookup exits with 1 and no output when nothing is stored
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(output) == 0 {
+		return "", ErrNoApiKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the api key from the keyring: %w", err)
+	}
+
+	key := strings.TrimSpace(string(output))
+	if key == "" {
+		return "", ErrNoApiKey
+	}
+	return key, nil
+}
+
+// the key is passed through stdin, so that it doesn't show up in the process list
+func writeToKeyring(profile, key string) error {
+	if !isKeyringAvailable() {
+		return fmt.Errorf("keyring store needs `%s` of libsecret, which is only available on Linux", secretTool)
+	}
+
+	cmd := exec.Command(
+		secretTool, "store",
+		"--label", fmt.Sprintf("nekot api key (%s)", profile),
+		"service", keyringService,
+		keyringAttribute, profile)
+	cmd.Stdin = strings.NewReader(key)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to save the api key to the keyring: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tearingItUp786/nekot/config"
+)
+
+const (
+	KeyringStore = "keyring"
+	FileStore    = "file"
+
+	apiKeyEnv     = "OPENAI_API_KEY"
+	passphraseEnv = "NEKOT_KEYS_PASSPHRASE"
+)
+
+var (
+	ErrNoApiKey           = errors.New("api key not found")
+	ErrPassphraseRequired = errors.New("passphrase is required to decrypt the keys file")
+)
+
+// the resolved key is kept in memory instead of the env, so that tools and mcp servers don't inherit it
+var (
+	apiKeyM sync.RWMutex
+	apiKey  string
+)
+
+func GetApiKey() string {
+	apiKeyM.RLock()
+	defer apiKeyM.RUnlock()
+	return apiKey
+}
+
+func SetApiKey(key string) {
+	apiKeyM.Lock()
+	defer apiKeyM.Unlock()
+	apiKey = key
+}
+
+// GetProfile returns the name the key is stored under. Every provider host has its own key
+func GetProfile(cfg config.Config) string {
+	if cfg.ApiKeyProfile != "" {
+		return cfg.ApiKeyProfile
+	}
+
+	parsedUrl, err := url.Parse(cfg.ChatGPTApiUrl)
+	if err != nil || parsedUrl.Host == "" {
+		return cfg.ChatGPTApiUrl
+	}
+	return parsedUrl.Host
+}
+
+// GetStore returns the configured store. Without one the keyring is used when it is available
+func GetStore(cfg config.Config) string {
+	if cfg.ApiKeyStore != "" {
+		return cfg.ApiKeyStore
+	}
+	if isKeyringAvailable() {
+		return KeyringStore
+	}
+	return FileStore
+}
+
+func GetPassphraseFromEnv() string {
+	return os.Getenv(passphraseEnv)
+}
+
+// IsPassphraseRequired reports whether reading or saving the key needs a passphrase
+func IsPassphraseRequired(cfg config.Config) bool {
+	return GetStore(cfg) == FileStore && GetPassphraseFromEnv() == ""
+}
+
+// ResolveApiKey looks for the key of the current profile in the key command,
+// the `OPENAI_API_KEY` env variable and the configured store, in that order
+func ResolveApiKey(cfg config.Config, passphrase string) (string, error) {
+	if cfg.ApiKeyCommand != "" {
+		key, err := runKeyCommand(cfg.ApiKeyCommand)
+		if err != nil {
+			return "", err
+		}
+		if key == "" {
+			return "", fmt.Errorf("apiKeyCommand `%s` printed an empty key", cfg.ApiKeyCommand)
+		}
+		return key, nil
+	}
+
+	if key := strings.TrimSpace(os.Getenv(apiKeyEnv)); key != "" {
+		return key, nil
+	}
+
+	profile := GetProfile(cfg)
+	switch GetStore(cfg) {
+	case KeyringStore:
+		return readFromKeyring(profile)
+	case FileStore:
+		return readFromFile(profile, passphrase)
+	}
+
+	return "", fmt.Errorf("unknown apiKeyStore %q, expected keyring or file", cfg.ApiKeyStore)
+}
+
+// SaveApiKey puts the key of the current profile into the configured store
+func SaveApiKey(cfg config.Config, key string, passphrase string) error {
+	profile := GetProfile(cfg)
+	switch GetStore(cfg) {
+	case KeyringStore:
+		return writeToKeyring(profile, key)
+	case FileStore:
+		return writeToFile(profile, key, passphrase)
+	}
+
+	return fmt.Errorf("unknown apiKeyStore %q, expected keyring or file", cfg.ApiKeyStore)
+}
@@ -0,0 +1,264 @@
+package sessions
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+type ContextStrategy string
+
+const (
+	KeepAllStrategy       ContextStrategy = "none"
+	DropOldestStrategy    ContextStrategy = "drop_oldest"
+	KeepFirstLastStrategy ContextStrategy = "keep_first_last"
+	SummarizeStrategy     ContextStrategy = "summarize"
+)
+
+var ContextStrategies = []ContextStrategy{
+	DropOldestStrategy,
+	KeepFirstLastStrategy,
+	SummarizeStrategy,
+	KeepAllStrategy,
+}
+
+const (
+	defaultKeepFirst = 2
+	defaultKeepLast  = 10
+	summaryPrefix    = "Summary of the earlier part of this conversation:\n"
+)
+
+func (s ContextStrategy) Next() ContextStrategy {
+	idx := slices.Index(ContextStrategies, s)
+	return ContextStrategies[(idx+1)%len(ContextStrategies)]
+}
+
+func (s ContextStrategy) Label() string {
+	switch s {
+	case DropOldestStrategy:
+		return "drop oldest"
+	case KeepFirstLastStrategy:
+		return "first+last"
+	case SummarizeStrategy:
+		return "summarize"
+	default:
+		return "keep all"
+	}
+}
+
+type contextWindow struct {
+	// amount of tokens the messages are allowed to take, 0 means unlimited
+	budget    int
+	keepFirst int
+	keepLast  int
+	model     string
+}
+
+func newContextWindow(cfg config.Config, settings util.Settings, systemMessage string) contextWindow {
+	window := contextWindow{
+		keepFirst: cfg.ContextWindow.KeepFirst,
+		keepLast:  cfg.ContextWindow.KeepLast,
+		model:     cfg.Azure.GetModelName(settings.Model),
+	}
+
+	if window.keepFirst <= 0 {
+		window.keepFirst = defaultKeepFirst
+	}
+	if window.keepLast <= 0 {
+		window.keepLast = defaultKeepLast
+	}
+
+	limit := util.GetContextLimit(window.model, cfg.ContextWindow.Limits)
+	if limit == 0 {
+		return window
+	}
+
+	// room has to be left for the answer and the system message
+	completionReserve := min(settings.MaxTokens, limit/2)
+	window.budget = limit - completionReserve - util.CountTokens(systemMessage, window.model)
+	return window
+}
+
+func (w contextWindow) fits(messages []util.MessageToSend) bool {
+	return w.budget <= 0 || util.CountMessagesTokens(messages, w.model) <= w.budget
+}
+
+// prepareContext applies the session context strategy to the messages that are about to be sent.
+// Summaries are generated by the model and stored with the session, so that they are reused
+func (m Orchestrator) prepareContext(ctx context.Context, messages []util.MessageToSend) ([]util.MessageToSend, error) {
+	window := newContextWindow(m.config, m.Settings, m.GetSystemMessage())
+	messages = removeIncompleteToolCalls(messages)
+
+	if window.fits(messages) {
+		return messages, nil
+	}
+
+	session, err := m.sessionService.GetSession(m.CurrentSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch session.ContextStrategy {
+	case DropOldestStrategy:
+		return dropOldest(messages, window), nil
+	case KeepFirstLastStrategy:
+		return keepFirstAndLast(messages, window), nil
+	case SummarizeStrategy:
+		return m.summarizeOlderMessages(ctx, session, messages, window)
+	}
+
+	return messages, nil
+}
+
+func (m Orchestrator) summarizeOlderMessages(
+	ctx context.Context,
+	session Session,
+	messages []util.MessageToSend,
+	window contextWindow,
+) ([]util.MessageToSend, error) {
+	summarizedCount := session.ContextSummarizedCount
+	summary := session.ContextSummary
+
+	// messages could have been removed since the summary was made
+	if summarizedCount > len(messages) {
+		summarizedCount = 0
+		summary = ""
+	}
+
+	if summary != "" {
+		withSummary := m.withSummary(summary, messages[summarizedCount:])
+		if window.fits(withSummary) {
+			return withSummary, nil
+		}
+	}
+
+	cut := safeCutIndex(messages, len(messages)-window.keepLast)
+	if cut <= summarizedCount {
+		return dropOldest(m.withSummary(summary, messages[summarizedCount:]), window), nil
+	}
+
+	requestStartedAt := time.Now()
+	newSummary, tokenUsage, err := m.OpenAiClient.RequestSummary(ctx, summary, messages[summarizedCount:cut], m.Settings)
+	if err != nil {
+		return nil, err
+	}
+	if tokenUsage != nil {
+		m.recordUsage(*tokenUsage, time.Since(requestStartedAt))
+	}
+
+	err = m.sessionService.UpdateSessionContextSummary(m.CurrentSessionID, newSummary, cut)
+	if err != nil {
+		return nil, err
+	}
+
+	return dropOldest(m.withSummary(newSummary, messages[cut:]), window), nil
+}
+
+func (m Orchestrator) withSummary(summary string, messages []util.MessageToSend) []util.MessageToSend {
+	if summary == "" {
+		return messages
+	}
+
+	role := "user"
+	if m.OpenAiClient.IsSystemMessageSupported(m.Settings.Model) {
+		role = "system"
+	}
+
+	summaryMessage := util.MessageToSend{Role: role, Content: summaryPrefix + summary}
+	return append([]util.MessageToSend{summaryMessage}, messages...)
+}
+
+// dropOldest removes messages from the start until the rest fits. A leading summary is preserved
+func dropOldest(messages []util.MessageToSend, window contextWindow) []util.MessageToSend {
+	var pinned []util.MessageToSend
+	if len(messages) > 0 && messages[0].Role == "system" {
+		pinned, messages = messages[:1], messages[1:]
+	}
+
+	if window.budget <= 0 {
+		return append(pinned, messages...)
+	}
+
+	// count every message once, tokenizing the whole history on every step is too slow
+	total := util.CountMessagesTokens(pinned, window.model)
+	counts := make([]int, len(messages))
+	for i, message := range messages {
+		counts[i] = util.CountMessageTokens(message, window.model)
+		total += counts[i]
+	}
+
+	start := 0
+	for start < len(messages)-1 && total > window.budget {
+		total -= counts[start]
+		start++
+	}
+	start = safeCutIndex(messages, start)
+
+	return removeIncompleteToolCalls(append(slices.Clone(pinned), messages[start:]...))
+}
+
+func keepFirstAndLast(messages []util.MessageToSend, window contextWindow) []util.MessageToSend {
+	if len(messages) <= window.keepFirst+window.keepLast {
+		return dropOldest(messages, window)
+	}
+
+	first := messages[:window.keepFirst]
+	last := messages[safeCutIndex(messages, len(messages)-window.keepLast):]
+	kept := removeIncompleteToolCalls(append(slices.Clone(first), last...))
+
+	if window.fits(kept) {
+		return kept
+	}
+
+	return dropOldest(kept, window)
+}
+
+// safeCutIndex moves the cut back, so that tool results are not separated from the call
+func safeCutIndex(messages []util.MessageToSend, cut int) int {
+	cut = max(cut, 0)
+	for cut > 0 && cut < len(messages) && messages[cut].Role == "tool" {
+		cut--
+	}
+	return cut
+}
+
+// The api rejects assistant tool calls without results as well as results without a call.
+// Tool calls without results lose the calls, results without a call are removed
+func removeIncompleteToolCalls(messages []util.MessageToSend) []util.MessageToSend {
+	results := map[string]bool{}
+	for _, message := range messages {
+		if message.ToolCallID != "" {
+			results[message.ToolCallID] = true
+		}
+	}
+
+	calls := map[string]bool{}
+	valid := []util.MessageToSend{}
+	for _, message := range messages {
+		if len(message.ToolCalls) > 0 {
+			isComplete := true
+			for _, toolCall := range message.ToolCalls {
+				isComplete = isComplete && results[toolCall.ID]
+			}
+
+			if isComplete {
+				for _, toolCall := range message.ToolCalls {
+					calls[toolCall.ID] = true
+				}
+			} else {
+				message.ToolCalls = nil
+			}
+		}
+
+		if message.ToolCallID != "" && !calls[message.ToolCallID] {
+			continue
+		}
+
+		valid = append(valid, message)
+	}
+
+	return valid
+}
@@ -2,6 +2,8 @@ package sessions
 
 import (
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tearingItUp786/nekot/mcp"
+	"github.com/tearingItUp786/nekot/tools"
 	"github.com/tearingItUp786/nekot/util"
 )
 
@@ -39,13 +41,96 @@ func SendUpdateCurrentSessionMsg(session Session) tea.Cmd {
 type ResponseChunkProcessed struct {
 	PreviousMsgArray []util.MessageToSend
 	ChunkMessage     string
+	ChunkReasoning   string
 }
 
-func SendResponseChunkProcessedMsg(msg string, previousMsgs []util.MessageToSend) tea.Cmd {
+func SendResponseChunkProcessedMsg(msg, reasoning string, previousMsgs []util.MessageToSend) tea.Cmd {
 	return func() tea.Msg {
 		return ResponseChunkProcessed{
 			PreviousMsgArray: previousMsgs,
 			ChunkMessage:     msg,
+			ChunkReasoning:   reasoning,
 		}
 	}
 }
+
+// Sent once the model finished streaming a response that asks for tool calls
+type ToolCallsRequested struct {
+	ToolCalls []util.ToolCall
+}
+
+func SendToolCallsRequestedMsg(toolCalls []util.ToolCall) tea.Cmd {
+	return func() tea.Msg {
+		return ToolCallsRequested{
+			ToolCalls: toolCalls,
+		}
+	}
+}
+
+// User's answer to the tool calls confirmation prompt
+type ToolCallsDecision struct {
+	Approved bool
+}
+
+func SendToolCallsDecisionMsg(approved bool) tea.Cmd {
+	return func() tea.Msg {
+		return ToolCallsDecision{
+			Approved: approved,
+		}
+	}
+}
+
+// Tool messages that need to be fed back to the model
+type ToolResultsReady struct {
+	Results []util.MessageToSend
+}
+
+// Tools collected from the configured MCP servers
+type McpServersStarted struct {
+	Tools   []tools.Tool
+	Clients []*mcp.Client
+	Errors  []error
+}
+
+// Asks to write the current session into a file of the working directory
+type ExportSessionRequested struct {
+	Format ExportFormat
+}
+
+func SendExportSessionRequestedMsg(format ExportFormat) tea.Cmd {
+	return func() tea.Msg {
+		return ExportSessionRequested{Format: format}
+	}
+}
+
+type SessionExported struct {
+	Path string
+}
+
+// Requests that change the current session, they are handled by the sessions pane
+type RenameSessionRequested struct {
+	Name string
+}
+
+func SendRenameSessionRequestedMsg(name string) tea.Cmd {
+	return func() tea.Msg {
+		return RenameSessionRequested{Name: name}
+	}
+}
+
+// An empty SystemMessage restores the one from the config
+type SystemMessageChangeRequested struct {
+	SystemMessage string
+}
+
+func SendSystemMessageChangeRequestedMsg(systemMessage string) tea.Cmd {
+	return func() tea.Msg {
+		return SystemMessageChangeRequested{SystemMessage: systemMessage}
+	}
+}
+
+type ClearSessionRequested struct{}
+
+func SendClearSessionRequestedMsg() tea.Msg {
+	return ClearSessionRequested{}
+}
@@ -0,0 +1,100 @@
+package sessions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tearingItUp786/nekot/util"
+)
+
+type ExportFormat string
+
+const (
+	MarkdownExport ExportFormat = "md"
+	JsonExport     ExportFormat = "json"
+)
+
+var ExportFormats = []ExportFormat{MarkdownExport, JsonExport}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type exportedSession struct {
+	Name     string               `json:"name"`
+	Messages []util.MessageToSend `json:"messages"`
+}
+
+// ExportSession writes the messages into dir, the file is named after the session.
+// Existing files are kept, a number is added to the name instead. Returns the path of the file
+func ExportSession(name string, messages []util.MessageToSend, format ExportFormat, dir string) (string, error) {
+	var content []byte
+	switch format {
+	case MarkdownExport:
+		content = []byte(formatMarkdown(name, messages))
+	case JsonExport:
+		data, err := json.MarshalIndent(exportedSession{Name: name, Messages: messages}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		content = append(data, '\n')
+	default:
+		return "", fmt.Errorf("unknown export format %q", format)
+	}
+
+	baseName := strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "-"), "-")
+	if baseName == "" {
+		baseName = "session"
+	}
+
+	for i := 1; ; i++ {
+		fileName := fmt.Sprintf("%s.%s", baseName, format)
+		if i > 1 {
+			fileName = fmt.Sprintf("%s-%d.%s", baseName, i, format)
+		}
+
+		path := filepath.Join(dir, fileName)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return path, err
+	}
+}
+
+// formatMarkdown leaves out the reasoning, the same way the chat hides it by default
+func formatMarkdown(name string, messages []util.MessageToSend) string {
+	var sb strings.Builder
+	sb.WriteString("# " + name + "\n")
+
+	for _, message := range messages {
+		switch message.Role {
+		case "user":
+			sb.WriteString("\n## User\n\n" + message.Content + "\n")
+		case "system":
+			sb.WriteString("\n## System\n\n" + message.Content + "\n")
+		case "tool":
+			sb.WriteString("\n## Tool result\n\n```\n" + message.Content + "\n```\n")
+		case "assistant":
+			if message.Content != "" {
+				sb.WriteString("\n## Assistant\n\n" + message.Content + "\n")
+			}
+			for _, toolCall := range message.ToolCalls {
+				sb.WriteString(fmt.Sprintf("\n## Tool call\n\n`%s %s`\n", toolCall.Function.Name, toolCall.Function.Arguments))
+			}
+		}
+	}
+
+	return sb.String()
+}
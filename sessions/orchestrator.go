@@ -4,13 +4,18 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
+	"time"
 
 	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/tearingItUp786/nekot/clients"
 	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/mcp"
 	"github.com/tearingItUp786/nekot/settings"
+	"github.com/tearingItUp786/nekot/tools"
+	"github.com/tearingItUp786/nekot/usage"
 	"github.com/tearingItUp786/nekot/user"
 	"github.com/tearingItUp786/nekot/util"
 	"golang.org/x/net/context"
@@ -26,6 +31,9 @@ type Orchestrator struct {
 	sessionService  *SessionService
 	userService     *user.UserService
 	settingsService *settings.SettingsService
+	usageService    *usage.UsageService
+	toolRegistry    *tools.Registry
+	mcpClients      []*mcp.Client
 	config          config.Config
 
 	OpenAiClient         *clients.OpenAiClient
@@ -35,12 +43,16 @@ type Orchestrator struct {
 	ArrayOfProcessResult []clients.ProcessApiCompletionResponse
 	ArrayOfMessages      []util.MessageToSend
 	CurrentAnswer        string
+	CurrentReasoning     string
 	AllSessions          []Session
 	ProcessingMode       string
+	PendingToolCalls     []util.ToolCall
+	SessionSystemMessage string
 
-	settingsReady bool
-	dataLoaded    bool
-	initialized   bool
+	settingsReady    bool
+	dataLoaded       bool
+	initialized      bool
+	requestStartedAt time.Time
 }
 
 func NewOrchestrator(db *sql.DB, ctx context.Context) Orchestrator {
@@ -54,7 +66,7 @@ func NewOrchestrator(db *sql.DB, ctx context.Context) Orchestrator {
 	}
 
 	settingsService := settings.NewSettingsService(db)
-	openAiClient := clients.NewOpenAiClient(config.ChatGPTApiUrl, config.SystemMessage)
+	openAiClient := clients.NewOpenAiClient(*config)
 
 	return Orchestrator{
 		config:               *config,
@@ -62,6 +74,8 @@ func NewOrchestrator(db *sql.DB, ctx context.Context) Orchestrator {
 		sessionService:       ss,
 		userService:          us,
 		settingsService:      settingsService,
+		usageService:         usage.NewUsageService(db),
+		toolRegistry:         tools.NewRegistry(*config),
 		OpenAiClient:         openAiClient,
 		ProcessingMode:       IDLE,
 	}
@@ -109,7 +123,23 @@ func (m Orchestrator) Init() tea.Cmd {
 		return dbLoadEvent
 	}
 
-	return tea.Batch(settingsData, dbData)
+	if len(m.config.McpServers) == 0 {
+		return tea.Batch(settingsData, dbData)
+	}
+
+	mcpServers := func() tea.Msg {
+		tools, clients, errs := tools.StartMcpServers(m.config.McpServers)
+		return McpServersStarted{Tools: tools, Clients: clients, Errors: errs}
+	}
+
+	return tea.Batch(settingsData, dbData, mcpServers)
+}
+
+// Shutdown stops the processes started by the orchestrator
+func (m Orchestrator) Shutdown() {
+	for _, client := range m.mcpClients {
+		client.Close()
+	}
 }
 
 func (m Orchestrator) Update(msg tea.Msg) (Orchestrator, tea.Cmd) {
@@ -128,15 +158,20 @@ func (m Orchestrator) Update(msg tea.Msg) (Orchestrator, tea.Cmd) {
 		clipboard.WriteAll(m.GetMessagesAsString())
 		cmds = append(cmds, util.SendNotificationMsg(util.CopiedNotification))
 
+	case ExportSessionRequested:
+		cmds = append(cmds, m.exportSession(msg.Format))
+
 	case UpdateCurrentSession:
 		m.CurrentSessionID = msg.Session.ID
 		m.CurrentSessionName = msg.Session.SessionName
 		m.ArrayOfMessages = msg.Session.Messages
+		m.SessionSystemMessage = msg.Session.SystemMessage
 
 	case LoadDataFromDB:
 		m.CurrentSessionID = msg.CurrentActiveSessionID
 		m.CurrentSessionName = msg.Session.SessionName
 		m.ArrayOfMessages = msg.Session.Messages
+		m.SessionSystemMessage = msg.Session.SystemMessage
 		m.AllSessions = msg.AllSessions
 		m.dataLoaded = true
 
@@ -144,10 +179,39 @@ func (m Orchestrator) Update(msg tea.Msg) (Orchestrator, tea.Cmd) {
 		m.Settings = msg.Settings
 		m.settingsReady = true
 
+	// tools and mcp servers are started once, changes to them need a restart
+	case config.ConfigChanged:
+		m.config = msg.Config
+		m.OpenAiClient = clients.NewOpenAiClient(msg.Config)
+
+	case util.ProcessingStateChanged:
+		if msg.IsProcessing {
+			m.requestStartedAt = time.Now()
+		}
+
+	case McpServersStarted:
+		m.mcpClients = msg.Clients
+		for _, tool := range msg.Tools {
+			m.toolRegistry.Register(tool)
+		}
+		if len(msg.Errors) > 0 {
+			cmds = append(cmds, util.MakeErrorMsg(errors.Join(msg.Errors...).Error()))
+		}
+
+	case ToolCallsDecision:
+		cmds = append(cmds, m.runPendingToolCalls(msg.Approved))
+
+	case ToolResultsReady:
+		m.ArrayOfMessages = append(m.ArrayOfMessages, msg.Results...)
+		err := m.sessionService.UpdateSessionMessages(m.CurrentSessionID, m.ArrayOfMessages)
+		if err != nil {
+			cmds = append(cmds, m.resetStateAndCreateError(err.Error()))
+		}
+
 	case clients.ProcessApiCompletionResponse:
 		// add the latest message to the array of messages
 		cmds = append(cmds, m.handleMsgProcessing(msg))
-		cmds = append(cmds, SendResponseChunkProcessedMsg(m.CurrentAnswer, m.ArrayOfMessages))
+		cmds = append(cmds, SendResponseChunkProcessedMsg(m.CurrentAnswer, m.CurrentReasoning, m.ArrayOfMessages))
 	}
 
 	if m.dataLoaded && m.settingsReady && !m.initialized {
@@ -159,7 +223,85 @@ func (m Orchestrator) Update(msg tea.Msg) (Orchestrator, tea.Cmd) {
 }
 
 func (m Orchestrator) GetCompletion(ctx context.Context, resp chan clients.ProcessApiCompletionResponse) tea.Cmd {
-	return m.OpenAiClient.RequestCompletion(ctx, m.ArrayOfMessages, m.Settings, resp)
+	return func() tea.Msg {
+		err := m.usageService.CheckBudget(m.config.Budget)
+		if err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+
+		messages, err := m.prepareContext(ctx, m.ArrayOfMessages)
+		if err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+
+		client := m.OpenAiClient.WithSystemMessage(m.GetSystemMessage())
+		requestCompletion := client.RequestCompletion(ctx, messages, m.Settings, m.toolRegistry.Definitions(), resp)
+		return requestCompletion()
+	}
+}
+
+// GetSystemMessage prefers the system message of the session over the one from the config
+func (m Orchestrator) GetSystemMessage() string {
+	if m.SessionSystemMessage != "" {
+		return m.SessionSystemMessage
+	}
+	return m.config.SystemMessage
+}
+
+// runs approved tool calls in the background. Declined calls still need an answer,
+// since the api refuses conversations with tool calls that have no results
+func (m *Orchestrator) runPendingToolCalls(approved bool) tea.Cmd {
+	toolCalls := m.PendingToolCalls
+	m.PendingToolCalls = nil
+	registry := m.toolRegistry
+
+	return func() tea.Msg {
+		results := []util.MessageToSend{}
+		for _, toolCall := range toolCalls {
+			if !approved {
+				results = append(results, tools.ConstructToolMessage(toolCall.ID, tools.DeclinedOutput))
+				continue
+			}
+			results = append(results, registry.Execute(toolCall))
+		}
+
+		return ToolResultsReady{Results: results}
+	}
+}
+
+func (m Orchestrator) exportSession(format ExportFormat) tea.Cmd {
+	name := m.CurrentSessionName
+	messages := m.ArrayOfMessages
+
+	return func() tea.Msg {
+		dir, err := os.Getwd()
+		if err != nil {
+			return util.ErrorEvent{Message: err.Error()}
+		}
+
+		path, err := ExportSession(name, messages, format, dir)
+		if err != nil {
+			return util.ErrorEvent{Message: "Failed to export the session: " + err.Error()}
+		}
+		return SessionExported{Path: path}
+	}
+}
+
+// RemoveLastAnswer drops everything after the last prompt, so that the prompt can be answered again
+func (m *Orchestrator) RemoveLastAnswer() error {
+	lastPromptIdx := -1
+	for i, message := range m.ArrayOfMessages {
+		if message.Role == "user" {
+			lastPromptIdx = i
+		}
+	}
+
+	if lastPromptIdx < 0 {
+		return errors.New("Nothing to regenerate, the session has no prompts")
+	}
+
+	m.ArrayOfMessages = m.ArrayOfMessages[:lastPromptIdx+1]
+	return m.sessionService.UpdateSessionMessages(m.CurrentSessionID, m.ArrayOfMessages)
 }
 
 func (m Orchestrator) GetLatestBotMessage() (string, error) {
@@ -195,6 +337,7 @@ func (m Orchestrator) GetMessagesAsString() string {
 func (m *Orchestrator) appendAndOrderProcessResults(msg clients.ProcessApiCompletionResponse) {
 	m.ArrayOfProcessResult = append(m.ArrayOfProcessResult, msg)
 	m.CurrentAnswer = ""
+	m.CurrentReasoning = ""
 
 	// we need to sort on ID here because go routines are done in different threads
 	// and the order in which our channel receives messages is not guaranteed.
@@ -207,16 +350,21 @@ func (m *Orchestrator) appendAndOrderProcessResults(msg clients.ProcessApiComple
 func (m *Orchestrator) assertChoiceContentString(choice clients.Choice) (string, tea.Cmd) {
 	choiceContent, ok := choice.Delta["content"]
 
-	if !ok {
-		if choice.FinishReason == "stop" || choice.FinishReason == "length" {
+	if !ok || choiceContent == nil {
+		if choice.IsFinished() {
 
 			areIdsAllThere := areIDsInOrderAndComplete(getArrayOfIDs(m.ArrayOfProcessResult))
 			var cmd tea.Cmd
 			if areIdsAllThere && m.ProcessingMode == PROCESSING {
-				cmd = m.handleFinalChoiceMessage()
+				cmd = m.handleFinalChoiceMessage(false)
 			}
 			return "", cmd
 		}
+
+		// content is either null or missing when the model streams tool calls or reasoning
+		if ok || choice.GetToolCallDeltas() != nil || choice.GetReasoningDelta() != "" {
+			return "", nil
+		}
 		return "", m.resetStateAndCreateError("choice content not found")
 	}
 	choiceString, ok := choiceContent.(string)
@@ -239,30 +387,45 @@ func constructJsonMessage(arrayOfProcessResult []clients.ProcessApiCompletionRes
 
 		if len(aMessage.Result.Choices) > 0 {
 			choice := aMessage.Result.Choices[0]
-			// TODO: we need a helper for this
-			if choice.FinishReason == "stop" || choice.FinishReason == "length" {
-				util.Log("Hit stop or length in construct")
+			if choice.IsFinished() {
+				util.Log("Hit stop, length or tool_calls in construct")
 				break
 			}
 
-			if content, ok := choice.Delta["content"].(string); ok {
+			switch content := choice.Delta["content"].(type) {
+			case string:
 				newMessage.Content += content
-			} else {
+			case nil:
+				// chunks with tool calls have no content
+			default:
 				// Handle the case where the type assertion fails, e.g., log an error or return
 				util.Log("type assertion to string failed for choice.Delta[\"content\"]")
 				formattedError := fmt.Errorf("type assertion to string failed for choice.Delta[\"content\"]")
 				return util.MessageToSend{}, formattedError
 			}
 
+			newMessage.Reasoning += choice.GetReasoningDelta()
+			newMessage.ToolCalls = clients.MergeToolCallDeltas(newMessage.ToolCalls, choice.GetToolCallDeltas())
 		}
 	}
+
+	// some models put their reasoning into the content wrapped with <think> tags
+	if newMessage.Reasoning == "" {
+		newMessage.Reasoning, newMessage.Content = util.SplitThinkingBlock(newMessage.Content)
+	}
+
 	return newMessage, nil
 }
 
-func (m *Orchestrator) handleFinalChoiceMessage() tea.Cmd {
+func (m *Orchestrator) handleFinalChoiceMessage(isInterrupted bool) tea.Cmd {
 	// if the json for whatever reason is malformed, bail out
 	jsonMessages, err := constructJsonMessage(m.ArrayOfProcessResult)
 
+	// tool calls of an interrupted stream are incomplete and can't be answered
+	if isInterrupted {
+		jsonMessages.ToolCalls = nil
+	}
+
 	m.ArrayOfMessages = append(
 		m.ArrayOfMessages,
 		jsonMessages,
@@ -275,6 +438,7 @@ func (m *Orchestrator) handleFinalChoiceMessage() tea.Cmd {
 	err = m.sessionService.UpdateSessionMessages(m.CurrentSessionID, m.ArrayOfMessages)
 	m.ProcessingMode = IDLE
 	m.CurrentAnswer = ""
+	m.CurrentReasoning = ""
 	m.ArrayOfProcessResult = []clients.ProcessApiCompletionResponse{}
 
 	if err != nil {
@@ -282,9 +446,30 @@ func (m *Orchestrator) handleFinalChoiceMessage() tea.Cmd {
 		return m.resetStateAndCreateError(err.Error())
 	}
 
+	if len(jsonMessages.ToolCalls) > 0 {
+		m.PendingToolCalls = jsonMessages.ToolCalls
+		return tea.Batch(
+			util.SendProcessingStateChangedMsg(false),
+			SendToolCallsRequestedMsg(jsonMessages.ToolCalls))
+	}
+
 	return util.SendProcessingStateChangedMsg(false)
 }
 
+func (m Orchestrator) recordUsage(tokenUsage clients.TokenUsage, latency time.Duration) {
+	err := m.usageService.InsertRecord(usage.Record{
+		SessionID:        m.CurrentSessionID,
+		Model:            m.Settings.Model,
+		PromptTokens:     tokenUsage.Prompt,
+		CompletionTokens: tokenUsage.Completion,
+		Cost:             usage.CalculateCost(m.config.Azure.GetModelName(m.Settings.Model), tokenUsage.Prompt, tokenUsage.Completion, m.config.Pricing),
+		Latency:          latency,
+	})
+	if err != nil {
+		util.Log("Failed to record usage", err)
+	}
+}
+
 func areIDsInOrderAndComplete(ids []int) bool {
 	if len(ids) == 0 {
 		return false // Assuming the list shouldn't be empty
@@ -309,8 +494,14 @@ func getArrayOfIDs(arr []clients.ProcessApiCompletionResponse) []int {
 
 // updates the current view with the messages coming in
 func (m *Orchestrator) handleMsgProcessing(msg clients.ProcessApiCompletionResponse) tea.Cmd {
+	if msg.Retry != nil {
+		retry := *msg.Retry
+		return func() tea.Msg { return retry }
+	}
+
 	if msg.Result.Usage != nil {
 		m.sessionService.UpdateSessionTokens(m.CurrentSessionID, msg.Result.Usage.Prompt, msg.Result.Usage.Completion)
+		m.recordUsage(*msg.Result.Usage, time.Since(m.requestStartedAt))
 	}
 
 	m.appendAndOrderProcessResults(msg)
@@ -320,7 +511,7 @@ func (m *Orchestrator) handleMsgProcessing(msg clients.ProcessApiCompletionRespo
 	if msg.Err != nil {
 		if errors.Is(context.Canceled, msg.Err) {
 			return tea.Batch(
-				m.handleFinalChoiceMessage(),
+				m.handleFinalChoiceMessage(true),
 				util.SendNotificationMsg(util.CancelledNotification),
 				util.SendProcessingStateChangedMsg(false))
 		}
@@ -330,7 +521,7 @@ func (m *Orchestrator) handleMsgProcessing(msg clients.ProcessApiCompletionRespo
 	for _, msg := range m.ArrayOfProcessResult {
 		if msg.Final && areIdsAllThere {
 			util.Log("-----Final message found-----")
-			return tea.Batch(m.handleFinalChoiceMessage(), util.SendProcessingStateChangedMsg(false))
+			return tea.Batch(m.handleFinalChoiceMessage(false), util.SendProcessingStateChangedMsg(false))
 		}
 
 		if len(msg.Result.Choices) > 0 {
@@ -345,6 +536,7 @@ func (m *Orchestrator) handleMsgProcessing(msg clients.ProcessApiCompletionRespo
 			}
 
 			m.CurrentAnswer = m.CurrentAnswer + choiceString
+			m.CurrentReasoning = m.CurrentReasoning + choice.GetReasoningDelta()
 		}
 	}
 
@@ -355,5 +547,6 @@ func (m *Orchestrator) resetStateAndCreateError(errMsg string) tea.Cmd {
 	m.ProcessingMode = ERROR
 	m.ArrayOfProcessResult = []clients.ProcessApiCompletionResponse{}
 	m.CurrentAnswer = ""
+	m.CurrentReasoning = ""
 	return util.MakeErrorMsg(errMsg)
 }
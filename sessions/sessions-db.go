@@ -8,12 +8,20 @@ import (
 )
 
 type Session struct {
-	ID               int
-	Messages         []util.MessageToSend
-	CreatedAt        string
-	SessionName      string
-	PromptTokens     int
-	CompletionTokens int
+	ID                     int
+	Messages               []util.MessageToSend
+	CreatedAt              string
+	SessionName            string
+	PromptTokens           int
+	CompletionTokens       int
+	ContextStrategy        ContextStrategy
+	ContextSummary         string
+	ContextSummarizedCount int
+	// SystemMessage replaces the one from the config when it is set
+	SystemMessage string
+	// unsent prompt, restored in the editor when DraftInEditor is set
+	Draft         string
+	DraftInEditor bool
 }
 
 type SessionService struct {
@@ -56,7 +64,9 @@ SELECT sessions_id, sessions_messages, sessions_created_at, sessions_session_nam
 func (ss *SessionService) GetSession(id int) (Session, error) {
 	var messages string
 	rows, err := ss.DB.Query(
-		`SELECT sessions_id, sessions_messages, sessions_created_at, sessions_session_name, prompt_tokens, completion_tokens FROM sessions WHERE sessions_id=$1`,
+		`SELECT sessions_id, sessions_messages, sessions_created_at, sessions_session_name, prompt_tokens, completion_tokens,
+			context_strategy, context_summary, context_summarized_count, system_message, draft, draft_in_editor
+		FROM sessions WHERE sessions_id=$1`,
 		id,
 	)
 	if err != nil {
@@ -69,7 +79,20 @@ func (ss *SessionService) GetSession(id int) (Session, error) {
 	aSession := Session{}
 	if rows.Next() {
 		// Check for errors from Scan.
-		if err := rows.Scan(&aSession.ID, &messages, &aSession.CreatedAt, &aSession.SessionName, &aSession.PromptTokens, &aSession.CompletionTokens); err != nil {
+		if err := rows.Scan(
+			&aSession.ID,
+			&messages,
+			&aSession.CreatedAt,
+			&aSession.SessionName,
+			&aSession.PromptTokens,
+			&aSession.CompletionTokens,
+			&aSession.ContextStrategy,
+			&aSession.ContextSummary,
+			&aSession.ContextSummarizedCount,
+			&aSession.SystemMessage,
+			&aSession.Draft,
+			&aSession.DraftInEditor,
+		); err != nil {
 			return Session{}, err
 		}
 	} else {
@@ -144,6 +167,49 @@ func (ss *SessionService) UpdateSessionTokens(id int, promptTokens, completionTo
 	return nil
 }
 
+func (ss *SessionService) UpdateSessionContextStrategy(id int, strategy ContextStrategy) error {
+	_, err := ss.DB.Exec(`
+			UPDATE sessions
+			SET context_strategy = $1
+			WHERE sessions_id = $2
+	`, strategy, id)
+
+	return err
+}
+
+// summarizedCount is the amount of leading session messages the summary replaces
+func (ss *SessionService) UpdateSessionContextSummary(id int, summary string, summarizedCount int) error {
+	_, err := ss.DB.Exec(`
+			UPDATE sessions
+			SET
+				context_summary = $1,
+				context_summarized_count = $2
+			WHERE sessions_id = $3
+	`, summary, summarizedCount, id)
+
+	return err
+}
+
+func (ss *SessionService) UpdateSessionSystemMessage(id int, systemMessage string) error {
+	_, err := ss.DB.Exec(`
+			UPDATE sessions
+			SET system_message = $1
+			WHERE sessions_id = $2
+	`, systemMessage, id)
+
+	return err
+}
+
+func (ss *SessionService) UpdateSessionDraft(id int, draft string, inEditor bool) error {
+	_, err := ss.DB.Exec(`
+			UPDATE sessions
+			SET draft = $1, draft_in_editor = $2
+			WHERE sessions_id = $3
+	`, draft, inEditor, id)
+
+	return err
+}
+
 func (ss *SessionService) UpdateSessionName(id int, name string) error {
 	_, err := ss.DB.Exec(`
 			UPDATE sessions
@@ -34,12 +34,17 @@ func NewSettingsService(db *sql.DB) *SettingsService {
 
 func (ss *SettingsService) GetSettings(ctx context.Context, cfg config.Config) tea.Msg {
 	settings := util.Settings{}
+	var temperature sql.NullFloat64
 	row := ss.DB.QueryRow(
-		`select settings_id, settings_model, settings_max_tokens, settings_frequency from settings`,
+		`select settings_id, settings_model, settings_max_tokens, settings_frequency, settings_reasoning_effort, settings_temperature
+		from settings`,
 	)
-	err := row.Scan(&settings.ID, &settings.Model, &settings.MaxTokens, &settings.Frequency)
+	err := row.Scan(&settings.ID, &settings.Model, &settings.MaxTokens, &settings.Frequency, &settings.ReasoningEffort, &temperature)
+	if temperature.Valid {
+		settings.Temperature = &temperature.Float64
+	}
 
-	availableModels, modelsError := ss.GetProviderModels(cfg.ChatGPTApiUrl)
+	availableModels, modelsError := ss.GetProviderModels(cfg)
 
 	if modelsError != nil {
 		return util.ErrorEvent{Message: modelsError.Error()}
@@ -76,11 +81,14 @@ func (ss *SettingsService) GetSettings(ctx context.Context, cfg config.Config) t
 	}
 }
 
-func (ss *SettingsService) GetProviderModels(apiUrl string) ([]string, error) {
+func (ss *SettingsService) GetProviderModels(cfg config.Config) ([]string, error) {
+	apiUrl := cfg.ChatGPTApiUrl
 	provider := util.GetInferenceProvider(apiUrl)
 	availableModels := []string{}
 
-	if provider != util.Local {
+	// local models and Azure deployments are cheap to get, so they are never cached
+	isCacheable := provider != util.Local && provider != util.Azure
+	if isCacheable {
 		var cacheErr error
 		availableModels, cacheErr = ss.TryGetModelsCache(int(provider))
 		if cacheErr != nil {
@@ -89,7 +97,7 @@ func (ss *SettingsService) GetProviderModels(apiUrl string) ([]string, error) {
 	}
 
 	if len(availableModels) == 0 {
-		openAiClient := clients.NewOpenAiClient(apiUrl, "")
+		openAiClient := clients.NewOpenAiClient(cfg)
 		modelsResponse := openAiClient.RequestModelsList()
 		if modelsResponse.Err != nil {
 			return []string{}, modelsResponse.Err
@@ -97,7 +105,7 @@ func (ss *SettingsService) GetProviderModels(apiUrl string) ([]string, error) {
 
 		availableModels = util.GetFilteredModelList(apiUrl, modelsResponse.Result.GetModelNames())
 
-		if provider == util.Local {
+		if !isCacheable {
 			return availableModels, nil
 		}
 
@@ -166,13 +174,15 @@ func (ss *SettingsService) CacheModelsForProvider(provider int, models []string)
 func (ss *SettingsService) UpdateSettings(newSettings util.Settings) (util.Settings, error) {
 	upsert := `
 		INSERT INTO settings 
-			(settings_id, settings_model, settings_max_tokens, settings_frequency)
+			(settings_id, settings_model, settings_max_tokens, settings_frequency, settings_reasoning_effort, settings_temperature)
 		VALUES
-			($1, $2, $3, $4)
+			($1, $2, $3, $4, $5, $6)
 		ON CONFLICT(settings_id) DO UPDATE SET
 			settings_model=$2,
 			settings_max_tokens=$3,
-			settings_frequency=$4;
+			settings_frequency=$4,
+			settings_reasoning_effort=$5,
+			settings_temperature=$6;
 	`
 
 	_, err := ss.DB.Exec(
@@ -181,6 +191,8 @@ func (ss *SettingsService) UpdateSettings(newSettings util.Settings) (util.Setti
 		newSettings.Model,
 		newSettings.MaxTokens,
 		newSettings.Frequency,
+		newSettings.ReasoningEffort,
+		newSettings.Temperature,
 	)
 	if err != nil {
 		return newSettings, err
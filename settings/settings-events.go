@@ -15,3 +15,25 @@ func MakeSettingsUpdateMsg(s util.Settings, err error) tea.Cmd {
 		return UpdateSettingsEvent{Settings: s, Err: err}
 	}
 }
+
+// Asks the settings pane to switch to the model, unknown models are reported as errors
+type ModelChangeRequested struct {
+	Model string
+}
+
+func SendModelChangeRequestedMsg(model string) tea.Cmd {
+	return func() tea.Msg {
+		return ModelChangeRequested{Model: model}
+	}
+}
+
+// nil Temperature resets it to the provider default
+type TemperatureChangeRequested struct {
+	Temperature *float64
+}
+
+func SendTemperatureChangeRequestedMsg(temperature *float64) tea.Cmd {
+	return func() tea.Msg {
+		return TemperatureChangeRequested{Temperature: temperature}
+	}
+}
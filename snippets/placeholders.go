@@ -0,0 +1,32 @@
+package snippets
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// {{name}}, spaces around the name are allowed
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// GetPlaceholders returns the names in the order of their first use, a repeated placeholder is asked for once
+func GetPlaceholders(content string) []string {
+	placeholders := []string{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		if !slices.Contains(placeholders, match[1]) {
+			placeholders = append(placeholders, match[1])
+		}
+	}
+	return placeholders
+}
+
+// FillPlaceholders keeps the placeholders without a value as they are
+func FillPlaceholders(content string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		name := strings.TrimSpace(strings.Trim(placeholder, "{}"))
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
@@ -0,0 +1,75 @@
+package snippets
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Snippet is a prompt template, its {{placeholders}} are filled in before it is inserted
+type Snippet struct {
+	ID      int
+	Name    string
+	Content string
+}
+
+type SnippetsService struct {
+	DB *sql.DB
+}
+
+func NewSnippetsService(db *sql.DB) *SnippetsService {
+	return &SnippetsService{
+		DB: db,
+	}
+}
+
+func (ss *SnippetsService) GetSnippets() ([]Snippet, error) {
+	rows, err := ss.DB.Query(`SELECT snippets_id, snippets_name, snippets_content FROM snippets ORDER BY snippets_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []Snippet{}
+	for rows.Next() {
+		snippet := Snippet{}
+		if err := rows.Scan(&snippet.ID, &snippet.Name, &snippet.Content); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, snippet)
+	}
+
+	return snippets, rows.Err()
+}
+
+func (ss *SnippetsService) GetSnippet(name string) (Snippet, error) {
+	snippet := Snippet{}
+	row := ss.DB.QueryRow(`SELECT snippets_id, snippets_name, snippets_content FROM snippets WHERE snippets_name=$1`, name)
+	err := row.Scan(&snippet.ID, &snippet.Name, &snippet.Content)
+	if err == sql.ErrNoRows {
+		return snippet, fmt.Errorf("No snippet named %s", name)
+	}
+	return snippet, err
+}
+
+// SaveSnippet replaces the content of an existing snippet with the same name
+func (ss *SnippetsService) SaveSnippet(name, content string) error {
+	_, err := ss.DB.Exec(`
+		INSERT INTO snippets (snippets_name, snippets_content) VALUES ($1, $2)
+		ON CONFLICT(snippets_name) DO UPDATE SET snippets_content=excluded.snippets_content
+	`, name, content)
+
+	return err
+}
+
+func (ss *SnippetsService) DeleteSnippet(name string) error {
+	result, err := ss.DB.Exec(`DELETE FROM snippets WHERE snippets_name=$1`, name)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err == nil && deleted == 0 {
+		return fmt.Errorf("No snippet named %s", name)
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package snippets
+
+import tea "github.com/charmbracelet/bubbletea"
+
+type SaveSnippetRequested struct {
+	Name    string
+	Content string
+}
+
+func SendSaveSnippetRequestedMsg(name, content string) tea.Cmd {
+	return func() tea.Msg {
+		return SaveSnippetRequested{Name: name, Content: content}
+	}
+}
+
+type DeleteSnippetRequested struct {
+	Name string
+}
+
+func SendDeleteSnippetRequestedMsg(name string) tea.Cmd {
+	return func() tea.Msg {
+		return DeleteSnippetRequested{Name: name}
+	}
+}
+
+// An empty Name opens the snippets picker
+type InsertSnippetRequested struct {
+	Name string
+}
+
+func SendInsertSnippetRequestedMsg(name string) tea.Cmd {
+	return func() tea.Msg {
+		return InsertSnippetRequested{Name: name}
+	}
+}
@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+)
+
+const (
+	maxToolOutputBytes = 64 * 1024
+	commandTimeout     = time.Second * 30
+	httpFetchTimeout   = time.Second * 15
+	maxFetchRedirects  = 10
+)
+
+func readFileTool(allowedRoots []string) Tool {
+	return Tool{
+		Name:        "read_file",
+		Description: "Read the contents of a text file on the user's machine",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the file, relative to the current working directory or absolute",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Execute: func(args map[string]interface{}) (string, error) {
+			path, err := getStringArg(args, "path")
+			if err != nil {
+				return "", err
+			}
+
+			if err := checkPath(path, allowedRoots); err != nil {
+				return "", err
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			defer file.Close()
+
+			content, err := io.ReadAll(io.LimitReader(file, maxToolOutputBytes))
+			if err != nil {
+				return "", err
+			}
+
+			return string(content), nil
+		},
+	}
+}
+
+func listDirectoryTool(allowedRoots []string) Tool {
+	return Tool{
+		Name:        "list_directory",
+		Description: "List files and directories inside of a directory on the user's machine",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the directory, relative to the current working directory or absolute",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Execute: func(args map[string]interface{}) (string, error) {
+			path, err := getStringArg(args, "path")
+			if err != nil {
+				return "", err
+			}
+
+			if err := checkPath(path, allowedRoots); err != nil {
+				return "", err
+			}
+
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return "", err
+			}
+
+			var sb strings.Builder
+			for _, entry := range entries {
+				name := entry.Name()
+				if entry.IsDir() {
+					name += string(filepath.Separator)
+				}
+				sb.WriteString(name)
+				sb.WriteString("\n")
+			}
+
+			return sb.String(), nil
+		},
+	}
+}
+
+func runCommandTool(allowedCommands []string) Tool {
+	return Tool{
+		Name: "run_command",
+		Description: "Run a program with arguments on the user's machine and return its combined output. " +
+			"The command is not run by a shell, pipes, redirects and variables are not supported. " +
+			"Allowed programs: " + strings.Join(allowedCommands, ", "),
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"command": map[string]interface{}{
+					"type":        "string",
+					"description": "The program and its arguments, quoted the way a shell would, for example `git commit -m \"fix bug\"`",
+				},
+			},
+			"required": []string{"command"},
+		},
+		Execute: func(args map[string]interface{}) (string, error) {
+			command, err := getStringArg(args, "command")
+			if err != nil {
+				return "", err
+			}
+
+			// the command is not passed through a shell, so pipes and redirects
+			// can't be used to sneak in a program that is not allowlisted.
+			// Only the quoting of a shell is understood, to keep arguments with spaces together
+			argv, err := shlex.Split(command)
+			if err != nil {
+				return "", fmt.Errorf("invalid command: %w", err)
+			}
+			if len(argv) == 0 {
+				return "", errors.New("command is empty")
+			}
+			if !slices.Contains(allowedCommands, argv[0]) {
+				return "", fmt.Errorf("command %s is not in the allowed commands list", argv[0])
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			defer cancel()
+
+			var output bytes.Buffer
+			cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+
+			err = cmd.Run()
+			result := truncateOutput(output.String())
+			if err != nil {
+				return fmt.Sprintf("%s\n%s", result, err.Error()), nil
+			}
+
+			return result, nil
+		},
+	}
+}
+
+func httpFetchTool(allowedHosts []string) Tool {
+	return Tool{
+		Name: "http_fetch",
+		Description: "Perform an HTTP GET request and return the response body. " +
+			"Allowed hosts: " + strings.Join(allowedHosts, ", "),
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "Absolute http(s) url to fetch",
+				},
+			},
+			"required": []string{"url"},
+		},
+		Execute: func(args map[string]interface{}) (string, error) {
+			rawUrl, err := getStringArg(args, "url")
+			if err != nil {
+				return "", err
+			}
+
+			parsedUrl, err := url.Parse(rawUrl)
+			if err != nil {
+				return "", err
+			}
+
+			if err := checkFetchUrl(parsedUrl, allowedHosts); err != nil {
+				return "", err
+			}
+
+			client := &http.Client{
+				Timeout: httpFetchTimeout,
+				// an allowed host must not be able to redirect to one that is not
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					if len(via) >= maxFetchRedirects {
+						return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+					}
+					return checkFetchUrl(req.URL, allowedHosts)
+				},
+			}
+			resp, err := client.Get(parsedUrl.String())
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxToolOutputBytes))
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("Status: %s\n\n%s", resp.Status, string(body)), nil
+		},
+	}
+}
+
+func checkFetchUrl(u *url.URL, allowedHosts []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme: %s", u.Scheme)
+	}
+
+	if !slices.Contains(allowedHosts, u.Hostname()) {
+		return fmt.Errorf("host %s is not in the allowed hosts list", u.Hostname())
+	}
+	return nil
+}
+
+// checkPath resolves symlinks first, so that a link inside of a root can't point outside of it
+func checkPath(path string, allowedRoots []string) error {
+	if len(allowedRoots) == 0 {
+		return nil
+	}
+
+	resolved, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if resolved, err = filepath.EvalSymlinks(resolved); err != nil {
+		return err
+	}
+
+	for _, root := range allowedRoots {
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		relative, err := filepath.Rel(resolvedRoot, resolved)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("path %s is outside of the allowed roots: %s", path, strings.Join(allowedRoots, ", "))
+}
+
+func truncateOutput(output string) string {
+	if len(output) > maxToolOutputBytes {
+		return output[:maxToolOutputBytes] + "\n...output truncated"
+	}
+	return output
+}
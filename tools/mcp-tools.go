@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/mcp"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	mcpToolNameSeparator = "__"
+	maxToolNameLength    = 64
+	toolNameHashLength   = 8
+)
+
+// function names are limited to ^[a-zA-Z0-9_-]{1,64}$ by the api
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// StartMcpServers launches every configured MCP server and collects the tools they expose.
+// A server that fails to start doesn't prevent the others from being used.
+func StartMcpServers(servers map[string]config.McpServerConfig) ([]Tool, []*mcp.Client, []error) {
+	tools := []Tool{}
+	clients := []*mcp.Client{}
+	errs := []error{}
+	toolNames := map[string]bool{}
+
+	names := []string{}
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		serverConfig := servers[name]
+		client, err := mcp.StartClient(name, serverConfig.Command, serverConfig.Args, serverConfig.Env)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		serverTools, err := getMcpServerTools(client)
+		if err != nil {
+			client.Close()
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", name, err))
+			continue
+		}
+
+		util.Log("mcp: loaded", len(serverTools), "tools from", name)
+		for _, tool := range serverTools {
+			// names of servers that differ in invalid chars only end up the same
+			if toolNames[tool.Name] {
+				err := fmt.Errorf("mcp server %s: tool %s is skipped, another server has a tool of the same name", name, tool.Name)
+				util.Log("mcp:", err)
+				errs = append(errs, err)
+				continue
+			}
+			toolNames[tool.Name] = true
+			tools = append(tools, tool)
+		}
+		clients = append(clients, client)
+	}
+
+	return tools, clients, errs
+}
+
+func getMcpServerTools(client *mcp.Client) ([]Tool, error) {
+	descriptions, err := client.ListTools()
+	if err != nil {
+		return nil, err
+	}
+
+	tools := []Tool{}
+	for _, description := range descriptions {
+		tools = append(tools, mcpTool(client, description))
+	}
+
+	// resources are optional, servers that don't support them answer with an error
+	resources, err := client.ListResources()
+	if err != nil {
+		util.Log("mcp: no resources for", client.Name, err)
+	}
+	if len(resources) > 0 {
+		tools = append(tools, mcpReadResourceTool(client, resources))
+	}
+
+	return tools, nil
+}
+
+func mcpTool(client *mcp.Client, description mcp.ToolDescription) Tool {
+	parameters := description.InputSchema
+	if parameters == nil {
+		parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+
+	toolName := description.Name
+	return Tool{
+		Name:        mcpToolName(client.Name, toolName),
+		Description: description.Description,
+		Parameters:  parameters,
+		Execute: func(args map[string]interface{}) (string, error) {
+			return client.CallTool(toolName, args)
+		},
+	}
+}
+
+func mcpReadResourceTool(client *mcp.Client, resources []mcp.ResourceDescription) Tool {
+	uris := []string{}
+	lines := []string{}
+	for _, resource := range resources {
+		uris = append(uris, resource.URI)
+		lines = append(lines, fmt.Sprintf("%s - %s %s", resource.URI, resource.Name, resource.Description))
+	}
+
+	return Tool{
+		Name:        mcpToolName(client.Name, "read_resource"),
+		Description: fmt.Sprintf("Read a resource provided by %s. Available resources:\n%s", client.Name, strings.Join(lines, "\n")),
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"uri": map[string]interface{}{
+					"type":        "string",
+					"description": "Uri of the resource to read",
+					"enum":        uris,
+				},
+			},
+			"required": []string{"uri"},
+		},
+		Execute: func(args map[string]interface{}) (string, error) {
+			uri, err := getStringArg(args, "uri")
+			if err != nil {
+				return "", err
+			}
+			return client.ReadResource(uri)
+		},
+	}
+}
+
+// mcpToolName prefixes the tool with its server. Names that are too long get a hash
+// of the full name in place of their end, so that tools sharing a long prefix stay apart
+func mcpToolName(serverName, toolName string) string {
+	fullName := serverName + mcpToolNameSeparator + toolName
+	name := invalidToolNameChars.ReplaceAllString(fullName, "_")
+	if len(name) > maxToolNameLength {
+		hash := sha256.Sum256([]byte(fullName))
+		suffix := "_" + hex.EncodeToString(hash[:])[:toolNameHashLength]
+		name = name[:maxToolNameLength-len(suffix)] + suffix
+	}
+	return name
+}
@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tearingItUp786/nekot/config"
+)
+
+const stubServerEnv = "NEKOT_MCP_STUB_SERVER"
+
+var longToolName = strings.Repeat("very_long_tool_name_", 4)
+
+// TestHelperProcess is not a real test, it is the stub MCP server started by the other tests
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(stubServerEnv) != "1" {
+		return
+	}
+	runStubServer()
+	os.Exit(0)
+}
+
+func runStubServer() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil || request.ID == nil {
+			continue
+		}
+
+		var params struct {
+			Cursor    string                 `json:"cursor"`
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		json.Unmarshal(request.Params, &params)
+
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": *request.ID}
+		switch {
+		case request.Method == "initialize":
+			response["result"] = map[string]interface{}{"protocolVersion": "2024-11-05", "capabilities": map[string]interface{}{}}
+
+		// the tools are split into two pages
+		case request.Method == "tools/list" && params.Cursor == "":
+			response["result"] = map[string]interface{}{
+				"tools":      []map[string]interface{}{{"name": "echo", "description": "Echo the text"}},
+				"nextCursor": "second",
+			}
+		case request.Method == "tools/list":
+			response["result"] = map[string]interface{}{
+				"tools": []map[string]interface{}{{"name": "fail"}, {"name": longToolName + "a"}, {"name": longToolName + "b"}},
+			}
+
+		case request.Method == "tools/call" && params.Name == "echo":
+			response["result"] = map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": fmt.Sprint(params.Arguments["text"])}},
+			}
+		case request.Method == "tools/call":
+			response["result"] = map[string]interface{}{
+				"content": []map[string]interface{}{{"type": "text", "text": "tool failed"}},
+				"isError": true,
+			}
+
+		// resources are not supported by the stub
+		default:
+			response["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+
+		payload, _ := json.Marshal(response)
+		fmt.Fprintln(os.Stdout, string(payload))
+	}
+}
+
+func stubServerConfig() config.McpServerConfig {
+	return config.McpServerConfig{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess"},
+		Env:     map[string]string{stubServerEnv: "1"},
+	}
+}
+
+func startStubServers(t *testing.T, servers map[string]config.McpServerConfig) (map[string]Tool, []error) {
+	t.Helper()
+	tools, clients, errs := StartMcpServers(servers)
+	t.Cleanup(func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	})
+
+	byName := map[string]Tool{}
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	return byName, errs
+}
+
+func TestStartMcpServers(t *testing.T) {
+	tools, errs := startStubServers(t, map[string]config.McpServerConfig{"stub": stubServerConfig()})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	// both pages are listed, there is no read_resource tool without resources
+	names := []string{}
+	for name := range tools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	expected := []string{
+		"stub__echo",
+		"stub__fail",
+		mcpToolName("stub", longToolName+"a"),
+		mcpToolName("stub", longToolName+"b"),
+	}
+	slices.Sort(expected)
+	if !slices.Equal(names, expected) {
+		t.Fatalf("expected tools %v, got %v", expected, names)
+	}
+
+	output, err := tools["stub__echo"].Execute(map[string]interface{}{"text": "hello"})
+	if err != nil || output != "hello" {
+		t.Errorf("expected the echoed text, got %q %v", output, err)
+	}
+
+	_, err = tools["stub__fail"].Execute(map[string]interface{}{})
+	if err == nil || err.Error() != "tool failed" {
+		t.Errorf("expected the error of the tool, got %v", err)
+	}
+}
+
+func TestStartMcpServersSkipsFailedAndClashingServers(t *testing.T) {
+	tools, errs := startStubServers(t, map[string]config.McpServerConfig{
+		"a.b":     stubServerConfig(),
+		"a_b":     stubServerConfig(),
+		"missing": {Command: "nekot-missing-mcp-server"},
+	})
+
+	// echo and fail of a_b clash with the ones of a.b, the long names keep the hash of the original name
+	if len(tools) != 6 {
+		t.Errorf("expected 6 tools, got %d", len(tools))
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected an error for the missing server and every clashing tool, got %v", errs)
+	}
+}
+
+func TestMcpToolName(t *testing.T) {
+	if name := mcpToolName("files.local", "read file"); name != "files_local__read_file" {
+		t.Errorf("expected invalid chars to be replaced, got %s", name)
+	}
+
+	first := mcpToolName("stub", longToolName+"a")
+	second := mcpToolName("stub", longToolName+"b")
+	if len(first) != maxToolNameLength || len(second) != maxToolNameLength {
+		t.Errorf("expected names of %d chars, got %d and %d", maxToolNameLength, len(first), len(second))
+	}
+	if first == second {
+		t.Errorf("expected truncated names to differ, both are %s", first)
+	}
+	if first != mcpToolName("stub", longToolName+"a") {
+		t.Error("expected the same name for the same tool")
+	}
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	toolRole         = "tool"
+	functionToolType = "function"
+	emptyToolOutput  = "(no output)"
+	DeclinedOutput   = "The user declined to run this tool call"
+)
+
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+	Execute     func(args map[string]interface{}) (string, error)
+}
+
+type Registry struct {
+	tools map[string]Tool
+}
+
+func NewRegistry(cfg config.Config) *Registry {
+	registry := &Registry{
+		tools: map[string]Tool{},
+	}
+
+	if !cfg.Tools.Enabled {
+		return registry
+	}
+
+	registry.Register(readFileTool(cfg.Tools.AllowedRoots))
+	registry.Register(listDirectoryTool(cfg.Tools.AllowedRoots))
+
+	if len(cfg.Tools.AllowedCommands) > 0 {
+		registry.Register(runCommandTool(cfg.Tools.AllowedCommands))
+	}
+
+	if len(cfg.Tools.AllowedHosts) > 0 {
+		registry.Register(httpFetchTool(cfg.Tools.AllowedHosts))
+	}
+
+	return registry
+}
+
+func (r *Registry) Register(tool Tool) {
+	r.tools[tool.Name] = tool
+}
+
+func (r *Registry) IsEmpty() bool {
+	return len(r.tools) == 0
+}
+
+// Definitions returns the tools in the format expected by the `tools` request param
+func (r *Registry) Definitions() []util.ToolDefinition {
+	definitions := []util.ToolDefinition{}
+	for _, tool := range r.tools {
+		definitions = append(definitions, util.ToolDefinition{
+			Type: functionToolType,
+			Function: util.ToolFunctionSchema{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	// map iteration order is random, keep the payload stable between requests
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Function.Name < definitions[j].Function.Name
+	})
+
+	return definitions
+}
+
+// Execute runs the requested tool call and wraps the output into a `tool` message.
+// Errors are reported back to the model as the tool output so it can react to them.
+func (r *Registry) Execute(call util.ToolCall) util.MessageToSend {
+	output, err := r.run(call)
+	if err != nil {
+		util.Log("Tool call failed:", call.Function.Name, err)
+		output = fmt.Sprintf("Error: %s", err.Error())
+	}
+
+	if output == "" {
+		output = emptyToolOutput
+	}
+
+	return ConstructToolMessage(call.ID, output)
+}
+
+func (r *Registry) run(call util.ToolCall) (string, error) {
+	tool, ok := r.tools[call.Function.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", call.Function.Name)
+	}
+
+	args := map[string]interface{}{}
+	if call.Function.Arguments != "" {
+		err := json.Unmarshal([]byte(call.Function.Arguments), &args)
+		if err != nil {
+			return "", fmt.Errorf("malformed tool arguments: %w", err)
+		}
+	}
+
+	return tool.Execute(args)
+}
+
+func ConstructToolMessage(toolCallID, content string) util.MessageToSend {
+	return util.MessageToSend{
+		Role:       toolRole,
+		Content:    content,
+		ToolCallID: toolCallID,
+	}
+}
+
+func getStringArg(args map[string]interface{}, name string) (string, error) {
+	value, ok := args[name]
+	if !ok {
+		return "", fmt.Errorf("missing required argument: %s", name)
+	}
+
+	str, ok := value.(string)
+	if !ok || str == "" {
+		return "", fmt.Errorf("argument %s must be a non-empty string", name)
+	}
+
+	return str, nil
+}
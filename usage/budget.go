@@ -0,0 +1,47 @@
+package usage
+
+import (
+	"fmt"
+
+	"github.com/tearingItUp786/nekot/config"
+)
+
+type BudgetStatus int
+
+const (
+	WithinBudget BudgetStatus = iota
+	SoftLimitReached
+	HardLimitReached
+)
+
+func GetBudgetStatus(monthlyCost float64, budget config.BudgetConfig) BudgetStatus {
+	switch {
+	case budget.MonthlyHardLimit > 0 && monthlyCost >= budget.MonthlyHardLimit:
+		return HardLimitReached
+	case budget.MonthlySoftLimit > 0 && monthlyCost >= budget.MonthlySoftLimit:
+		return SoftLimitReached
+	default:
+		return WithinBudget
+	}
+}
+
+// CheckBudget returns an error once the hard limit of the current month is reached
+func (us *UsageService) CheckBudget(budget config.BudgetConfig) error {
+	if budget.MonthlyHardLimit <= 0 {
+		return nil
+	}
+
+	monthlyCost, err := us.GetMonthlyCost()
+	if err != nil {
+		return err
+	}
+
+	if GetBudgetStatus(monthlyCost, budget) == HardLimitReached {
+		return fmt.Errorf(
+			"Monthly budget of %s is reached (%s spent). Requests are blocked until the next month, raise `budget.monthlyHardLimit` in the config to continue",
+			FormatCost(budget.MonthlyHardLimit),
+			FormatCost(monthlyCost))
+	}
+
+	return nil
+}
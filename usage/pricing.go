@@ -0,0 +1,76 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tearingItUp786/nekot/config"
+)
+
+const tokensPerPricingUnit = 1_000_000
+
+type modelPrice struct {
+	modelPrefix string
+	pricing     config.ModelPricing
+}
+
+// Known prices in USD per million tokens, the longest matching prefix is used.
+// Models that are not listed, local ones included, are considered free
+var defaultPrices = []modelPrice{
+	{"gpt-4o-mini", config.ModelPricing{Input: 0.15, Output: 0.6}},
+	{"gpt-4o", config.ModelPricing{Input: 2.5, Output: 10}},
+	{"chatgpt-4o", config.ModelPricing{Input: 5, Output: 15}},
+	{"gpt-4.1-nano", config.ModelPricing{Input: 0.1, Output: 0.4}},
+	{"gpt-4.1-mini", config.ModelPricing{Input: 0.4, Output: 1.6}},
+	{"gpt-4.1", config.ModelPricing{Input: 2, Output: 8}},
+	{"gpt-4.5", config.ModelPricing{Input: 75, Output: 150}},
+	{"gpt-4-turbo", config.ModelPricing{Input: 10, Output: 30}},
+	{"gpt-4-32k", config.ModelPricing{Input: 60, Output: 120}},
+	{"gpt-4", config.ModelPricing{Input: 30, Output: 60}},
+	{"gpt-3.5-turbo", config.ModelPricing{Input: 0.5, Output: 1.5}},
+	{"gpt-5-nano", config.ModelPricing{Input: 0.05, Output: 0.4}},
+	{"gpt-5-mini", config.ModelPricing{Input: 0.25, Output: 2}},
+	{"gpt-5", config.ModelPricing{Input: 1.25, Output: 10}},
+	{"o1-mini", config.ModelPricing{Input: 1.1, Output: 4.4}},
+	{"o1-pro", config.ModelPricing{Input: 150, Output: 600}},
+	{"o1", config.ModelPricing{Input: 15, Output: 60}},
+	{"o3-mini", config.ModelPricing{Input: 1.1, Output: 4.4}},
+	{"o3-pro", config.ModelPricing{Input: 20, Output: 80}},
+	{"o3", config.ModelPricing{Input: 2, Output: 8}},
+	{"o4-mini", config.ModelPricing{Input: 1.1, Output: 4.4}},
+	{"mistral-large", config.ModelPricing{Input: 2, Output: 6}},
+	{"mistral-medium", config.ModelPricing{Input: 0.4, Output: 2}},
+	{"mistral-small", config.ModelPricing{Input: 0.2, Output: 0.6}},
+	{"codestral", config.ModelPricing{Input: 0.3, Output: 0.9}},
+	{"open-mistral-nemo", config.ModelPricing{Input: 0.15, Output: 0.15}},
+}
+
+// GetModelPricing returns the prices of a model.
+// Prices from the config take precedence over the built-in ones
+func GetModelPricing(model string, overrides map[string]config.ModelPricing) config.ModelPricing {
+	if pricing, ok := overrides[model]; ok {
+		return pricing
+	}
+
+	// gpt-4.5 must not be billed as gpt-4, whatever the order of the entries
+	match := modelPrice{}
+	for _, price := range defaultPrices {
+		if strings.HasPrefix(model, price.modelPrefix) && len(price.modelPrefix) > len(match.modelPrefix) {
+			match = price
+		}
+	}
+
+	return match.pricing
+}
+
+func CalculateCost(model string, promptTokens, completionTokens int, overrides map[string]config.ModelPricing) float64 {
+	pricing := GetModelPricing(model, overrides)
+	return (float64(promptTokens)*pricing.Input + float64(completionTokens)*pricing.Output) / tokensPerPricingUnit
+}
+
+func FormatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
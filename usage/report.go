@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+var reportSections = []struct {
+	title    string
+	grouping ReportGrouping
+}{
+	{"Usage per day", ByDay},
+	{"Usage per model", ByModel},
+	{"Usage per session", BySession},
+}
+
+// WriteReport prints usage tables grouped by day, model and session
+func (us *UsageService) WriteReport(w io.Writer) error {
+	monthlyCost, err := us.GetMonthlyCost()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Spent this month: %s\n", FormatCost(monthlyCost))
+
+	for _, section := range reportSections {
+		report, err := us.GetReport(section.grouping)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "\n%s\n", section.title)
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "\tREQUESTS\tIN\tOUT\tCOST\tAVG LATENCY")
+		for _, row := range report {
+			fmt.Fprintf(
+				table,
+				"%s\t%d\t%d\t%d\t%s\t%s\n",
+				row.Key,
+				row.Requests,
+				row.PromptTokens,
+				row.CompletionTokens,
+				FormatCost(row.Cost),
+				row.AverageLatency.Round(time.Millisecond*100))
+		}
+		table.Flush()
+	}
+
+	return nil
+}
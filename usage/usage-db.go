@@ -0,0 +1,172 @@
+package usage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type Record struct {
+	SessionID        int
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	// time from sending the request until the whole answer is received
+	Latency time.Duration
+}
+
+// ReportRow aggregates the usage of a single day, model or session
+type ReportRow struct {
+	Key              string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	AverageLatency   time.Duration
+}
+
+type DailyUsage struct {
+	Day      time.Time
+	Requests int
+	Tokens   int
+}
+
+type ReportGrouping string
+
+const (
+	ByDay     ReportGrouping = "day"
+	ByModel   ReportGrouping = "model"
+	BySession ReportGrouping = "session"
+)
+
+type UsageService struct {
+	DB *sql.DB
+}
+
+func NewUsageService(db *sql.DB) *UsageService {
+	return &UsageService{
+		DB: db,
+	}
+}
+
+func (us *UsageService) InsertRecord(record Record) error {
+	_, err := us.DB.Exec(`
+		INSERT INTO usage (usage_session_id, usage_model, usage_prompt_tokens, usage_completion_tokens, usage_cost, usage_latency_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, record.SessionID, record.Model, record.PromptTokens, record.CompletionTokens, record.Cost, record.Latency.Milliseconds())
+
+	return err
+}
+
+func (us *UsageService) GetSessionCost(sessionID int) (float64, error) {
+	var cost float64
+	row := us.DB.QueryRow(`SELECT COALESCE(SUM(usage_cost), 0) FROM usage WHERE usage_session_id = $1`, sessionID)
+	err := row.Scan(&cost)
+	return cost, err
+}
+
+// GetMonthlyCost sums up the cost of the current calendar month in local time
+func (us *UsageService) GetMonthlyCost() (float64, error) {
+	var cost float64
+	row := us.DB.QueryRow(`
+		SELECT COALESCE(SUM(usage_cost), 0) FROM usage
+		WHERE strftime('%Y-%m', usage_created_at, 'localtime') = strftime('%Y-%m', 'now', 'localtime')
+	`)
+	err := row.Scan(&cost)
+	return cost, err
+}
+
+func (us *UsageService) GetAverageLatency() (time.Duration, error) {
+	var latencyMs float64
+	row := us.DB.QueryRow(`SELECT COALESCE(AVG(NULLIF(usage_latency_ms, 0)), 0) FROM usage`)
+	err := row.Scan(&latencyMs)
+	return time.Duration(latencyMs) * time.Millisecond, err
+}
+
+func (us *UsageService) GetReport(grouping ReportGrouping) ([]ReportRow, error) {
+	var key, groupBy, order string
+	switch grouping {
+	case ByDay:
+		key = "date(usage_created_at, 'localtime')"
+		groupBy = key
+		order = "key DESC"
+	case ByModel:
+		key = "usage_model"
+		groupBy = key
+		order = "cost DESC"
+	case BySession:
+		// usage of deleted sessions is kept, so that monthly budgets stay correct
+		key = "COALESCE(sessions_session_name, 'deleted session #' || usage_session_id)"
+		groupBy = "usage_session_id"
+		order = "cost DESC"
+	default:
+		return nil, fmt.Errorf("unknown report grouping: %s", grouping)
+	}
+
+	rows, err := us.DB.Query(fmt.Sprintf(`
+		SELECT %s AS key, COUNT(*), SUM(usage_prompt_tokens), SUM(usage_completion_tokens), SUM(usage_cost) AS cost,
+			COALESCE(AVG(NULLIF(usage_latency_ms, 0)), 0)
+		FROM usage
+		LEFT JOIN sessions ON sessions_id = usage_session_id
+		GROUP BY %s
+		ORDER BY %s
+	`, key, groupBy, order))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []ReportRow{}
+	for rows.Next() {
+		row := ReportRow{}
+		var latencyMs float64
+		err := rows.Scan(&row.Key, &row.Requests, &row.PromptTokens, &row.CompletionTokens, &row.Cost, &latencyMs)
+		if err != nil {
+			return nil, err
+		}
+		row.AverageLatency = time.Duration(latencyMs) * time.Millisecond
+		report = append(report, row)
+	}
+
+	return report, rows.Err()
+}
+
+// GetDailyUsage returns usage of the last days in local time, days without requests included
+func (us *UsageService) GetDailyUsage(days int) ([]DailyUsage, error) {
+	rows, err := us.DB.Query(`
+		SELECT date(usage_created_at, 'localtime') AS day, COUNT(*), SUM(usage_prompt_tokens + usage_completion_tokens)
+		FROM usage
+		WHERE date(usage_created_at, 'localtime') > date('now', 'localtime', $1)
+		GROUP BY day
+	`, fmt.Sprintf("-%d days", days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usageByDay := map[string]DailyUsage{}
+	for rows.Next() {
+		var day string
+		usage := DailyUsage{}
+		if err := rows.Scan(&day, &usage.Requests, &usage.Tokens); err != nil {
+			return nil, err
+		}
+		usageByDay[day] = usage
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	dailyUsage := []DailyUsage{}
+	for i := days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i)
+		usage := usageByDay[day.Format(time.DateOnly)]
+		usage.Day = day
+		dailyUsage = append(dailyUsage, usage)
+	}
+
+	return dailyUsage, nil
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type Background string
+
+const (
+	AutoBackground  Background = "auto"
+	DarkBackground  Background = "dark"
+	LightBackground Background = "light"
+)
+
+var (
+	detectBackgroundOnce sync.Once
+	isTerminalDark       = true
+	isLightBackground    atomic.Bool
+)
+
+// DetectBackground asks the terminal for its background color. It has to run before
+// the program takes over the terminal, otherwise the answer ends up in the input
+func DetectBackground() {
+	detectBackgroundOnce.Do(func() {
+		isTerminalDark = lipgloss.HasDarkBackground()
+		Log("Dark terminal background detected:", isTerminalDark)
+	})
+}
+
+// SetBackground picks the color variants, empty and `auto` use the detected background
+func SetBackground(background Background) {
+	switch background {
+	case DarkBackground:
+		isLightBackground.Store(false)
+	case LightBackground:
+		isLightBackground.Store(true)
+	default:
+		isLightBackground.Store(!isTerminalDark)
+	}
+}
+
+func IsDarkBackground() bool {
+	return !isLightBackground.Load()
+}
@@ -25,7 +25,19 @@ func GetAppDirName() string {
 	return "." + binaryName
 }
 
+// GetAppDataPath returns the folder of the database, logs and stored keys
 func GetAppDataPath() (string, error) {
+	return getAppPath("XDG_DATA_HOME")
+}
+
+// GetAppConfigPath returns the folder of the config file
+func GetAppConfigPath() (string, error) {
+	return getAppPath("XDG_CONFIG_HOME")
+}
+
+// getAppPath uses `$XDG_*_HOME/<binary>` when the variable is set, `~/.<binary>` otherwise.
+// Existing installations keep using the home folder until it is moved, so no history is lost
+func getAppPath(xdgEnv string) (string, error) {
 	// Get the user's home directory
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -38,6 +50,14 @@ func GetAppDataPath() (string, error) {
 	// Combine them to form the full path
 	fullPath := filepath.Join(homeDir, appDirName)
 
+	// the spec requires absolute paths, relative ones must be ignored
+	if xdgHome := os.Getenv(xdgEnv); filepath.IsAbs(xdgHome) {
+		xdgPath := filepath.Join(xdgHome, strings.TrimPrefix(appDirName, "."))
+		if isDir(xdgPath) || !isDir(fullPath) {
+			fullPath = xdgPath
+		}
+	}
+
 	// Optionally, create the directory if it doesn't already exist
 	err = os.MkdirAll(fullPath, 0755)
 	if err != nil {
@@ -47,6 +67,11 @@ func GetAppDataPath() (string, error) {
 	return fullPath, nil
 }
 
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
 //go:embed chat.db
 var dbEmbed embed.FS
 
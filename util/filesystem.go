@@ -19,7 +19,11 @@ func DeleteFilesIfDevMode() {
 			log.Println("Error deleting database file:", err)
 		}
 		// Delete the config file
-		pathToPersistedFile := filepath.Join(appPath, "config.json")
+		configPath, err := GetAppConfigPath()
+		if err != nil {
+			panic(err)
+		}
+		pathToPersistedFile := filepath.Join(configPath, "config.json")
 		err = os.Remove(pathToPersistedFile)
 		if err != nil {
 			log.Println("Error deleting config file:", err)
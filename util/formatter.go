@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -8,7 +9,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-func GetMessagesAsPrettyString(msgsToRender []MessageToSend, w int, colors SchemeColors) string {
+func GetMessagesAsPrettyString(msgsToRender []MessageToSend, w int, colors SchemeColors, showReasoning bool) string {
 	var messages string
 	for _, message := range msgsToRender {
 		messageToUse := message.Content
@@ -17,7 +18,14 @@ func GetMessagesAsPrettyString(msgsToRender []MessageToSend, w int, colors Schem
 		case message.Role == "user":
 			messageToUse = RenderUserMessage(messageToUse, w, colors, false)
 		case message.Role == "assistant":
-			messageToUse = RenderBotMessage(messageToUse, w, colors, false)
+			messageToUse = joinRendered(
+				RenderReasoning(message.Reasoning, w, colors, showReasoning),
+				RenderBotMessage(messageToUse, w, colors, false))
+			if len(message.ToolCalls) > 0 {
+				messageToUse = joinRendered(messageToUse, RenderToolCalls(message.ToolCalls, w, colors, false))
+			}
+		case message.Role == "tool":
+			messageToUse = RenderToolMessage(messageToUse, w, colors, false)
 		}
 
 		if messages == "" {
@@ -31,7 +39,7 @@ func GetMessagesAsPrettyString(msgsToRender []MessageToSend, w int, colors Schem
 	return messages
 }
 
-func GetVisualModeView(msgsToRender []MessageToSend, w int, colors SchemeColors) string {
+func GetVisualModeView(msgsToRender []MessageToSend, w int, colors SchemeColors, showReasoning bool) string {
 	var messages string
 	for _, message := range msgsToRender {
 		messageToUse := message.Content
@@ -40,7 +48,14 @@ func GetVisualModeView(msgsToRender []MessageToSend, w int, colors SchemeColors)
 		case message.Role == "user":
 			messageToUse = RenderUserMessage(messageToUse, w, colors, true)
 		case message.Role == "assistant":
-			messageToUse = RenderBotMessage(messageToUse, w, colors, true)
+			messageToUse = joinRendered(
+				RenderReasoning(message.Reasoning, w, colors, showReasoning),
+				RenderBotMessage(messageToUse, w, colors, true))
+			if len(message.ToolCalls) > 0 {
+				messageToUse = joinRendered(messageToUse, RenderToolCalls(message.ToolCalls, w, colors, true))
+			}
+		case message.Role == "tool":
+			messageToUse = RenderToolMessage(messageToUse, w, colors, true)
 		}
 
 		if messages == "" {
@@ -81,7 +96,11 @@ func RenderErrorMessage(msg string, width int, colors SchemeColors) string {
 		glamour.WithPreservedNewLines(),
 		colors.RendererThemeOption,
 	)
-	msg = "```json\n" + msg + "\n```"
+	// unparsed responses are shown as is, everything else is a readable message
+	trimmed := strings.TrimSpace(msg)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		msg = "```json\n" + msg + "\n```"
+	}
 	errMsg, _ := renderer.Render(msg)
 	output := strings.TrimSpace(errMsg)
 	return lipgloss.NewStyle().
@@ -119,6 +138,110 @@ func RenderBotMessage(msg string, width int, colors SchemeColors, isVisualMode b
 		Render(output)
 }
 
+const (
+	toolOutputPreviewLines = 10
+	thinkOpenTag           = "<think>"
+	thinkCloseTag          = "</think>"
+)
+
+// SplitThinkingBlock separates a leading <think>...</think> block from the answer.
+// While the block is not closed yet, the whole content is considered reasoning.
+func SplitThinkingBlock(content string) (reasoning string, answer string) {
+	trimmed := strings.TrimLeft(content, " \t\n")
+	if !strings.HasPrefix(trimmed, thinkOpenTag) {
+		return "", content
+	}
+
+	trimmed = strings.TrimPrefix(trimmed, thinkOpenTag)
+	reasoning, answer, found := strings.Cut(trimmed, thinkCloseTag)
+	if !found {
+		return strings.TrimSpace(trimmed), ""
+	}
+
+	return strings.TrimSpace(reasoning), strings.TrimLeft(answer, " \t\n")
+}
+
+// RenderReasoning renders a dimmed reasoning section, collapsed to a single line unless expanded
+func RenderReasoning(reasoning string, width int, colors SchemeColors, isExpanded bool) string {
+	reasoning = strings.TrimSpace(reasoning)
+	if reasoning == "" {
+		return ""
+	}
+
+	style := lipgloss.NewStyle().
+		Faint(true).
+		Foreground(colors.NormalTabBorderColor).
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeftForeground(colors.NormalTabBorderColor).
+		PaddingLeft(1).
+		MarginTop(1)
+
+	if !isExpanded {
+		linesCount := strings.Count(reasoning, "\n") + 1
+		return style.Render(fmt.Sprintf("💭 Reasoning (%d lines) - press r to expand", linesCount))
+	}
+
+	return style.Width(max(width-DefaultElementsPadding, 0)).Render("💭 Reasoning\n" + reasoning)
+}
+
+func RenderToolCalls(toolCalls []ToolCall, width int, colors SchemeColors, isVisualMode bool) string {
+	renderer, _ := glamour.NewTermRenderer(
+		glamour.WithPreservedNewLines(),
+		colors.RendererThemeOption,
+	)
+
+	msg := ""
+	for _, toolCall := range toolCalls {
+		msg += fmt.Sprintf("\n🔧 `%s` `%s`", toolCall.Function.Name, toolCall.Function.Arguments)
+	}
+
+	rendered, _ := renderer.Render(msg + "\n")
+	output := strings.TrimSpace(rendered)
+	if isVisualMode {
+		return lipgloss.NewStyle().Render("\n" + output + "\n")
+	}
+
+	return lipgloss.NewStyle().
+		BorderLeft(true).
+		BorderStyle(lipgloss.InnerHalfBlockBorder()).
+		BorderLeftForeground(colors.AccentColor).
+		Render("\n" + output + "\n")
+}
+
+// Tool outputs can be huge (whole files), so only a preview is shown in the chat
+func RenderToolMessage(msg string, width int, colors SchemeColors, isVisualMode bool) string {
+	renderer, _ := glamour.NewTermRenderer(
+		glamour.WithPreservedNewLines(),
+		colors.RendererThemeOption,
+	)
+
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	if len(lines) > toolOutputPreviewLines {
+		lines = append(lines[:toolOutputPreviewLines], "...")
+	}
+
+	msg = "\n📎 Tool output:\n```\n" + strings.Join(lines, "\n") + "\n```\n"
+	rendered, _ := renderer.Render(msg)
+	output := strings.TrimSpace(rendered)
+	if isVisualMode {
+		return lipgloss.NewStyle().Render("\n" + output + "\n")
+	}
+
+	return lipgloss.NewStyle().
+		BorderLeft(true).
+		BorderStyle(lipgloss.InnerHalfBlockBorder()).
+		BorderLeftForeground(colors.AccentColor).
+		Render(output)
+}
+
+func joinRendered(first, second string) string {
+	if first == "" {
+		return second
+	}
+	return first + "\n" + second
+}
+
 func StripAnsiCodes(str string) string {
 	ansiRegex := regexp.MustCompile(`\x1b\[[0-9;]*[mG]`)
 	return ansiRegex.ReplaceAllString(str, "")
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyScope groups bindings of a pane or mode, it is also the section name in the `keys` config.
+// Global bindings are active together with every other scope
+type KeyScope string
+
+const (
+	GlobalKeys       KeyScope = "global"
+	PromptKeys       KeyScope = "prompt"
+	ToolApprovalKeys KeyScope = "toolApproval"
+	ChatKeys         KeyScope = "chat"
+	SelectionKeys    KeyScope = "selection"
+	SessionsKeys     KeyScope = "sessions"
+	SettingsKeys     KeyScope = "settings"
+	PaletteKeys      KeyScope = "palette"
+	SnippetFormKeys  KeyScope = "snippetForm"
+)
+
+// KeyBindings exposes the fields of a key map by their names in the config
+type KeyBindings map[string]*key.Binding
+
+// key maps register themselves on init, overrides are applied before the panes copy them
+var (
+	keyScopes    = map[KeyScope]KeyBindings{}
+	keyConflicts = []string{}
+)
+
+// RegisterKeyBindings adds the bindings to the scope, several key maps may share a scope
+func RegisterKeyBindings(scope KeyScope, bindings KeyBindings) {
+	if keyScopes[scope] == nil {
+		keyScopes[scope] = KeyBindings{}
+	}
+	for action, binding := range bindings {
+		keyScopes[scope][action] = binding
+	}
+}
+
+func GetKeyScopes() []KeyScope {
+	scopes := []KeyScope{}
+	for scope := range keyScopes {
+		scopes = append(scopes, scope)
+	}
+	slices.Sort(scopes)
+	return scopes
+}
+
+// GetKeyActions returns the names of the bindings in the scope, false for unknown scopes
+func GetKeyActions(scope KeyScope) ([]string, bool) {
+	bindings, ok := keyScopes[scope]
+	if !ok {
+		return nil, false
+	}
+
+	actions := []string{}
+	for action := range bindings {
+		actions = append(actions, action)
+	}
+	slices.Sort(actions)
+	return actions, true
+}
+
+// ApplyKeyBindings replaces the keys of the overridden bindings, an empty list disables the binding.
+// Unknown scopes and actions are skipped, the config validation reports them.
+// Returns the conflicts of the resulting bindings
+func ApplyKeyBindings(overrides map[string]map[string][]string) []string {
+	for scope, actions := range overrides {
+		bindings := keyScopes[KeyScope(scope)]
+		for action, keys := range actions {
+			binding, ok := bindings[action]
+			if !ok {
+				continue
+			}
+
+			binding.SetKeys(keys...)
+			binding.SetHelp(strings.Join(keys, "/"), binding.Help().Desc)
+			binding.SetEnabled(len(keys) > 0)
+		}
+	}
+
+	keyConflicts = findKeyConflicts()
+	for _, conflict := range keyConflicts {
+		Log("Key conflict:", conflict)
+	}
+	return keyConflicts
+}
+
+// GetKeyConflicts returns the conflicts found when the bindings were applied
+func GetKeyConflicts() []string {
+	return keyConflicts
+}
+
+// findKeyConflicts reports keys bound to several actions of a scope, or to a scope and a global action,
+// since pressing the key would trigger both of them
+func findKeyConflicts() []string {
+	conflicts := []string{}
+
+	for _, scope := range GetKeyScopes() {
+		actionsByKey := map[string][]string{}
+		addActions := func(active KeyScope) {
+			for action, binding := range keyScopes[active] {
+				for _, k := range binding.Keys() {
+					actionsByKey[k] = append(actionsByKey[k], fmt.Sprintf("%s.%s", active, action))
+				}
+			}
+		}
+
+		addActions(GlobalKeys)
+		if scope != GlobalKeys {
+			addActions(scope)
+		}
+
+		for k, actions := range actionsByKey {
+			if len(actions) < 2 {
+				continue
+			}
+			slices.Sort(actions)
+			conflict := fmt.Sprintf("%s is bound to %s", k, strings.Join(actions, " and "))
+			if !slices.Contains(conflicts, conflict) {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+
+	slices.Sort(conflicts)
+	return conflicts
+}
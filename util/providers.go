@@ -18,6 +18,7 @@ var (
 var (
 	openAiApiPrefixes  = []string{"api.openai.com"}
 	mistralApiPrefixes = []string{"api.mistral.ai"}
+	azureApiPrefixes   = []string{"openai.azure.com", "cognitiveservices.azure.com"}
 	localApiPrefixes   = []string{"localhost", "127.0.0.1", "::1"}
 )
 
@@ -27,6 +28,7 @@ const (
 	OpenAi ApiProvider = iota
 	Local
 	Mistral
+	Azure
 )
 
 func GetFilteredModelList(apiUrl string, models []string) []string {
@@ -35,7 +37,7 @@ func GetFilteredModelList(apiUrl string, models []string) []string {
 
 	for _, model := range models {
 		switch provider {
-		case Local:
+		case Local, Azure:
 			modelNames = append(modelNames, model)
 		case OpenAi:
 			if isOpenAiChatModel(model) {
@@ -56,7 +58,7 @@ func IsSystemMessageSupported(provider ApiProvider, model string) bool {
 	switch provider {
 	case Local:
 		return true
-	case OpenAi:
+	case OpenAi, Azure:
 		if isOpenAiReasoningModel(model) {
 			return false
 		}
@@ -74,16 +76,21 @@ func TransformRequestHeaders(provider ApiProvider, params map[string]interface{}
 			"include_usage": true,
 		}
 		return params
-	case OpenAi:
+	case OpenAi, Azure:
 		params["stream_options"] = map[string]interface{}{
 			"include_usage": true,
 		}
 
+		// reasoning models only accept the default temperature
 		if isOpenAiReasoningModel(params["model"].(string)) {
 			delete(params, "max_tokens")
+			delete(params, "temperature")
+		} else {
+			delete(params, "reasoning_effort")
 		}
 		return params
 	case Mistral:
+		delete(params, "reasoning_effort")
 		return params
 	}
 
@@ -103,6 +110,12 @@ func GetInferenceProvider(apiUrl string) ApiProvider {
 		return Mistral
 	}
 
+	if slices.ContainsFunc(azureApiPrefixes, func(p string) bool {
+		return strings.Contains(apiUrl, p)
+	}) {
+		return Azure
+	}
+
 	if slices.ContainsFunc(localApiPrefixes, func(p string) bool {
 		return strings.Contains(apiUrl, p)
 	}) {
@@ -15,6 +15,8 @@ const (
 	Groovebox    ColorScheme = "Groove"
 )
 
+var ColorSchemes = []ColorScheme{OriginalPink, SmoothBlue, Groovebox}
+
 //go:embed glamour-styles/groovebox.json
 var grooveBoxThemeBytes []byte
 
@@ -24,6 +26,15 @@ var pinkThemeBytes []byte
 //go:embed glamour-styles/blue.json
 var blueThemeBytes []byte
 
+//go:embed glamour-styles/groovebox-light.json
+var grooveBoxLightThemeBytes []byte
+
+//go:embed glamour-styles/pink-light.json
+var pinkLightThemeBytes []byte
+
+//go:embed glamour-styles/blue-light.json
+var blueLightThemeBytes []byte
+
 var (
 	pink100   = "#F2B3E8"
 	pink200   = "#8C3A87"
@@ -52,6 +63,28 @@ var (
 	grooveboxYellow    = "#C0A568"
 )
 
+// light variants keep the hues of the dark ones, but are dark enough to be read on a light background
+var (
+	pinkLight100   = "#A3338F"
+	pinkLight300   = "#9C3A9E"
+	redLight       = "#B5133F"
+	darkGrey       = "#333333"
+	lightGreyDark  = "#8A8A8A"
+	smoothBlueDark = "#4F63A8"
+	pinkYellowDark = "#A0522D"
+	lightGreenDark = "#4E8A3E"
+	blueDark       = "#3F5AA8"
+)
+
+var (
+	grooveboxLightOrange = "#AF3A03"
+	grooveboxLightGreen  = "#79740E"
+	grooveboxLightBlue   = "#076678"
+	grooveboxLightRed    = "#9D0006"
+	grooveboxDarkGrey    = "#3C3836"
+	grooveboxLightYellow = "#B57614"
+)
+
 type SchemeColors struct {
 	MainColor            lipgloss.Color
 	AccentColor          lipgloss.Color
@@ -63,7 +96,20 @@ type SchemeColors struct {
 	RendererThemeOption  glamour.TermRendererOption
 }
 
+// GetColors returns the variant of the scheme that matches the terminal background
 func (s ColorScheme) GetColors() SchemeColors {
+	isDark := IsDarkBackground()
+	if colors, ok := getUserTheme(s, isDark); ok {
+		return colors
+	}
+	return s.getBuiltinColors(isDark)
+}
+
+func (s ColorScheme) getBuiltinColors(isDark bool) SchemeColors {
+	if !isDark {
+		return s.getLightColors()
+	}
+
 	defaultColors := SchemeColors{
 		MainColor:            lipgloss.Color(pink100),
 		AccentColor:          lipgloss.Color(pink200),
@@ -103,6 +149,49 @@ func (s ColorScheme) GetColors() SchemeColors {
 	case OriginalPink:
 		return defaultColors
 
+	default:
+		// unknown and broken themes fall back to the default scheme instead of failing
+		return defaultColors
+	}
+}
+
+func (s ColorScheme) getLightColors() SchemeColors {
+	defaultColors := SchemeColors{
+		MainColor:            lipgloss.Color(pinkLight100),
+		AccentColor:          lipgloss.Color(pink200),
+		HighlightColor:       lipgloss.Color(pinkLight300),
+		DefaultTextColor:     lipgloss.Color(darkGrey),
+		ErrorColor:           lipgloss.Color(redLight),
+		NormalTabBorderColor: lipgloss.Color(lightGreyDark),
+		ActiveTabBorderColor: lipgloss.Color(pinkLight300),
+		RendererThemeOption:  glamour.WithStylesFromJSONBytes(pinkLightThemeBytes),
+	}
+
+	switch s {
+	case SmoothBlue:
+		return SchemeColors{
+			MainColor:            lipgloss.Color(pinkYellowDark),
+			AccentColor:          lipgloss.Color(lightGreenDark),
+			HighlightColor:       lipgloss.Color(blueDark),
+			DefaultTextColor:     lipgloss.Color(darkGrey),
+			ErrorColor:           lipgloss.Color(redLight),
+			NormalTabBorderColor: lipgloss.Color(smoothBlueDark),
+			ActiveTabBorderColor: lipgloss.Color(pinkYellowDark),
+			RendererThemeOption:  glamour.WithStylesFromJSONBytes(blueLightThemeBytes),
+		}
+
+	case Groovebox:
+		return SchemeColors{
+			MainColor:            lipgloss.Color(grooveboxLightOrange),
+			AccentColor:          lipgloss.Color(grooveboxLightGreen),
+			HighlightColor:       lipgloss.Color(grooveboxLightBlue),
+			DefaultTextColor:     lipgloss.Color(grooveboxDarkGrey),
+			ErrorColor:           lipgloss.Color(grooveboxLightRed),
+			NormalTabBorderColor: lipgloss.Color(grooveboxLightYellow),
+			ActiveTabBorderColor: lipgloss.Color(grooveboxLightGreen),
+			RendererThemeOption:  glamour.WithStylesFromJSONBytes(grooveBoxLightThemeBytes),
+		}
+
 	default:
 		return defaultColors
 	}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"slices"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -24,6 +25,7 @@ const (
 	SessionsPane
 	PromptPane
 	ChatPane
+	UsagePane
 )
 
 const (
@@ -34,6 +36,12 @@ const (
 const (
 	CopiedNotification Notification = iota
 	CancelledNotification
+	ContextOverflowNotification
+	BudgetWarningNotification
+	ConfigReloadedNotification
+	ExportedNotification
+	SnippetSavedNotification
+	SnippetDeletedNotification
 )
 
 type ViewMode int
@@ -45,8 +53,8 @@ const (
 )
 
 var (
-	NormalFocusPanes = []Pane{SettingsPane, SessionsPane, PromptPane, ChatPane}
-	ZenFocusPanes    = []Pane{PromptPane, ChatPane}
+	NormalFocusPanes = []Pane{SettingsPane, SessionsPane, PromptPane, ChatPane, UsagePane}
+	ZenFocusPanes    = []Pane{PromptPane, ChatPane, UsagePane}
 )
 
 func IsFocusAllowed(mode ViewMode, pane Pane, tw int) bool {
@@ -122,6 +130,55 @@ func SendPromptReadyMsg(prompt string) tea.Cmd {
 	}
 }
 
+// PromptInsertRequested puts the text at the cursor of the prompt editor, the editor has to be open
+type PromptInsertRequested struct {
+	Text string
+}
+
+func SendPromptInsertRequestedMsg(text string) tea.Cmd {
+	return func() tea.Msg {
+		return PromptInsertRequested{Text: text}
+	}
+}
+
+// PromptHistorySearchRequested opens a picker with the sent prompts, newest first
+type PromptHistorySearchRequested struct {
+	Prompts []string
+}
+
+func SendPromptHistorySearchRequestedMsg(prompts []string) tea.Cmd {
+	return func() tea.Msg {
+		return PromptHistorySearchRequested{Prompts: prompts}
+	}
+}
+
+// PromptHistoryPicked replaces the typed prompt, multiline prompts need the editor to be open
+type PromptHistoryPicked struct {
+	Prompt string
+}
+
+func SendPromptHistoryPickedMsg(prompt string) tea.Cmd {
+	return func() tea.Msg {
+		return PromptHistoryPicked{Prompt: prompt}
+	}
+}
+
+type RetryScheduled struct {
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+}
+
+type PromptInputChanged struct {
+	Text string
+}
+
+func SendPromptInputChangedMsg(text string) tea.Cmd {
+	return func() tea.Msg {
+		return PromptInputChanged{Text: text}
+	}
+}
+
 type AsyncDependencyReady struct {
 	Dependency AsyncDependency
 }
@@ -172,6 +229,40 @@ func SendCopyAllMsgs() tea.Msg {
 	return CopyAllMsgs{}
 }
 
+// CopyUsageReportMsg copies the report of the --usage-report flag
+type CopyUsageReportMsg struct{}
+
+func SendCopyUsageReportMsg() tea.Msg {
+	return CopyUsageReportMsg{}
+}
+
+type NewSessionMsg struct{}
+
+func SendNewSessionMsg() tea.Msg {
+	return NewSessionMsg{}
+}
+
+// PickModelMsg opens the models picker of the settings pane, the pane has to be focused to use it
+type PickModelMsg struct{}
+
+func SendPickModelMsg() tea.Msg {
+	return PickModelMsg{}
+}
+
+// PickThemeMsg opens the themes picker of the settings pane, the pane has to be focused to use it
+type PickThemeMsg struct{}
+
+func SendPickThemeMsg() tea.Msg {
+	return PickThemeMsg{}
+}
+
+// RegenerateMsg drops the answer to the last prompt and requests a new one
+type RegenerateMsg struct{}
+
+func SendRegenerateMsg() tea.Msg {
+	return RegenerateMsg{}
+}
+
 type ViewModeChanged struct {
 	Mode ViewMode
 }
@@ -1,13 +1,43 @@
 package util
 
 type Settings struct {
-	ID        int
-	Model     string
-	MaxTokens int
-	Frequency int
+	ID              int
+	Model           string
+	MaxTokens       int
+	Frequency       int
+	ReasoningEffort string
+	// nil leaves the temperature to the provider
+	Temperature *float64
 }
 
 type MessageToSend struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Reasoning is persisted with the session, but is never sent back to the api
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolDefinition is the shape of a single entry of the `tools` request param
+type ToolDefinition struct {
+	Type     string             `json:"type"`
+	Function ToolFunctionSchema `json:"function"`
+}
+
+type ToolFunctionSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
 }
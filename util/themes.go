@@ -0,0 +1,257 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	themesDirName         = "themes"
+	themeFileExtension    = ".json"
+	glamourStyleExtension = ".glamour.json"
+)
+
+// ThemeFile is a user color scheme stored in `themes/<name>.json`. Colors are hex (`#ff00aa`)
+// or ANSI numbers (`205`), missing ones are taken from the Base scheme. GlamourStyle is a style file
+// relative to the themes folder, or one of the glamour styles: dark, light, dracula, notty, ascii, pink.
+// Light colors are used on light terminals, themes without them look the same on both backgrounds
+type ThemeFile struct {
+	Base ColorScheme `json:"base"`
+	ThemeColors
+	Light *ThemeColors `json:"light"`
+}
+
+type ThemeColors struct {
+	GlamourStyle         string `json:"glamourStyle"`
+	MainColor            string `json:"mainColor"`
+	AccentColor          string `json:"accentColor"`
+	HighlightColor       string `json:"highlightColor"`
+	DefaultTextColor     string `json:"defaultTextColor"`
+	ErrorColor           string `json:"errorColor"`
+	NormalTabBorderColor string `json:"normalTabBorderColor"`
+	ActiveTabBorderColor string `json:"activeTabBorderColor"`
+}
+
+type themeVariants struct {
+	dark  SchemeColors
+	light SchemeColors
+}
+
+// user themes are read from disk once and on every config reload, rendering only looks them up
+var (
+	userThemesM sync.RWMutex
+	userThemes  = map[ColorScheme]themeVariants{}
+)
+
+var hexColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func GetThemesPath() (string, error) {
+	configPath, err := GetAppConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configPath, themesDirName), nil
+}
+
+// GetColorSchemes returns the built-in schemes followed by the loaded user themes
+func GetColorSchemes() []ColorScheme {
+	userThemesM.RLock()
+	defer userThemesM.RUnlock()
+
+	names := []ColorScheme{}
+	for name := range userThemes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return append(slices.Clone(ColorSchemes), names...)
+}
+
+// IsColorSchemeAvailable also accepts themes that exist but failed to load, they fall back to the default colors
+func IsColorSchemeAvailable(scheme ColorScheme) bool {
+	if slices.Contains(ColorSchemes, scheme) {
+		return true
+	}
+
+	themesPath, err := GetThemesPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(themesPath, string(scheme)+themeFileExtension))
+	return err == nil
+}
+
+// LoadThemes replaces the user themes with the content of the themes folder.
+// Invalid themes are skipped or partially applied, the returned errors describe what went wrong
+func LoadThemes() []error {
+	themesPath, err := GetThemesPath()
+	if err != nil {
+		return []error{err}
+	}
+
+	entries, err := os.ReadDir(themesPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return []error{err}
+	}
+
+	themes := map[ColorScheme]themeVariants{}
+	errs := []error{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() ||
+			!strings.HasSuffix(fileName, themeFileExtension) ||
+			strings.HasSuffix(fileName, glamourStyleExtension) {
+			continue
+		}
+
+		name := ColorScheme(strings.TrimSuffix(fileName, themeFileExtension))
+		if slices.Contains(ColorSchemes, name) {
+			errs = append(errs, fmt.Errorf("theme %s: the name is taken by a built-in scheme", name))
+			continue
+		}
+
+		variants, themeErrs := loadTheme(themesPath, fileName)
+		errs = append(errs, themeErrs...)
+		if variants != nil {
+			themes[name] = *variants
+		}
+	}
+
+	for _, err := range errs {
+		Log("Failed to load theme:", err)
+	}
+
+	userThemesM.Lock()
+	userThemes = themes
+	userThemesM.Unlock()
+
+	return errs
+}
+
+// loadTheme returns nil variants only when the file can't be used at all
+func loadTheme(themesPath, fileName string) (*themeVariants, []error) {
+	name := strings.TrimSuffix(fileName, themeFileExtension)
+
+	content, err := os.ReadFile(filepath.Join(themesPath, fileName))
+	if err != nil {
+		return nil, []error{fmt.Errorf("theme %s: %w", name, err)}
+	}
+
+	var theme ThemeFile
+	if err := json.Unmarshal(content, &theme); err != nil {
+		return nil, []error{fmt.Errorf("theme %s: invalid JSON: %w", name, err)}
+	}
+
+	errs := []error{}
+	if theme.Base != "" && !slices.Contains(ColorSchemes, theme.Base) {
+		errs = append(errs, fmt.Errorf("theme %s: unknown base %q, using %s", name, theme.Base, OriginalPink))
+	}
+
+	dark, darkErrs := applyThemeColors(theme.Base.getBuiltinColors(true), theme.ThemeColors, themesPath)
+	for _, err := range darkErrs {
+		errs = append(errs, fmt.Errorf("theme %s: %w", name, err))
+	}
+
+	variants := themeVariants{dark: dark, light: dark}
+	if theme.Light != nil {
+		light, lightErrs := applyThemeColors(theme.Base.getBuiltinColors(false), *theme.Light, themesPath)
+		for _, err := range lightErrs {
+			errs = append(errs, fmt.Errorf("theme %s: light.%w", name, err))
+		}
+		variants.light = light
+	}
+
+	return &variants, errs
+}
+
+// applyThemeColors overrides the colors that are set and valid
+func applyThemeColors(colors SchemeColors, theme ThemeColors, themesPath string) (SchemeColors, []error) {
+	errs := []error{}
+	for field, color := range map[string]struct {
+		value  string
+		target *lipgloss.Color
+	}{
+		"mainColor":            {theme.MainColor, &colors.MainColor},
+		"accentColor":          {theme.AccentColor, &colors.AccentColor},
+		"highlightColor":       {theme.HighlightColor, &colors.HighlightColor},
+		"defaultTextColor":     {theme.DefaultTextColor, &colors.DefaultTextColor},
+		"errorColor":           {theme.ErrorColor, &colors.ErrorColor},
+		"normalTabBorderColor": {theme.NormalTabBorderColor, &colors.NormalTabBorderColor},
+		"activeTabBorderColor": {theme.ActiveTabBorderColor, &colors.ActiveTabBorderColor},
+	} {
+		if color.value == "" {
+			continue
+		}
+		if !isValidColor(color.value) {
+			errs = append(errs, fmt.Errorf("%s %q is not a hex or ANSI color", field, color.value))
+			continue
+		}
+		*color.target = lipgloss.Color(color.value)
+	}
+
+	if theme.GlamourStyle != "" {
+		rendererOption, err := loadGlamourStyle(themesPath, theme.GlamourStyle)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("glamourStyle: %w", err))
+		} else {
+			colors.RendererThemeOption = rendererOption
+		}
+	}
+
+	return colors, errs
+}
+
+func loadGlamourStyle(themesPath, style string) (glamour.TermRendererOption, error) {
+	if _, ok := glamour.DefaultStyles[style]; ok {
+		return glamour.WithStandardStyle(style), nil
+	}
+
+	path := style
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(themesPath, path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glamour style: %w", err)
+	}
+
+	// glamour only reports invalid styles when rendering, which would leave the chat empty
+	var styleConfig ansi.StyleConfig
+	if err := json.Unmarshal(content, &styleConfig); err != nil {
+		return nil, fmt.Errorf("invalid glamour style %s: %w", style, err)
+	}
+
+	return glamour.WithStyles(styleConfig), nil
+}
+
+func isValidColor(color string) bool {
+	if hexColorRegexp.MatchString(color) {
+		return true
+	}
+	number, err := strconv.Atoi(color)
+	return err == nil && number >= 0 && number <= 255
+}
+
+func getUserTheme(scheme ColorScheme, isDark bool) (SchemeColors, bool) {
+	userThemesM.RLock()
+	defer userThemesM.RUnlock()
+
+	variants, ok := userThemes[scheme]
+	if !isDark {
+		return variants.light, ok
+	}
+	return variants.dark, ok
+}
@@ -0,0 +1,158 @@
+package util
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	// rough average of characters per token, used until the tokenizer is loaded
+	charsPerToken = 4
+	// every message is wrapped with role and separator tokens
+	messageTokensOverhead = 3
+	// every reply is primed with the assistant role tokens
+	replyTokensOverhead = 3
+)
+
+const (
+	o200kEncoding  = "o200k_base"
+	cl100kEncoding = "cl100k_base"
+)
+
+// Models that use o200k. Everything else, including non OpenAI models, is counted with cl100k,
+// which is a close enough approximation for other BPE vocabularies
+var o200kModelPrefixes = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o"}
+
+type contextLimit struct {
+	modelPrefix string
+	tokens      int
+}
+
+// Known context windows, the longest matching prefix is used
+var defaultContextLimits = []contextLimit{
+	{"gpt-4.1", 1047576},
+	{"gpt-4.5", 128000},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"gpt-5", 400000},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"mistral-large", 128000},
+	{"mistral-medium", 128000},
+	{"mistral-small", 32000},
+	{"codestral", 256000},
+	{"open-mistral-nemo", 128000},
+	{"deepseek", 64000},
+}
+
+type tokenizer struct {
+	once     sync.Once
+	encoding *tiktoken.Tiktoken
+	ready    chan struct{}
+}
+
+var tokenizers = map[string]*tokenizer{
+	o200kEncoding:  {ready: make(chan struct{})},
+	cl100kEncoding: {ready: make(chan struct{})},
+}
+
+func init() {
+	// vocabularies are embedded into the binary, nothing is downloaded at runtime
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+func getEncodingName(model string) string {
+	for _, prefix := range o200kModelPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return o200kEncoding
+		}
+	}
+	return cl100kEncoding
+}
+
+// getTokenizer returns the tokenizer for the model if it is loaded.
+// Parsing a vocabulary takes a while, so the first call starts loading it in background
+func getTokenizer(model string) *tiktoken.Tiktoken {
+	t := tokenizers[getEncodingName(model)]
+	t.once.Do(func() {
+		go func(name string) {
+			encoding, err := tiktoken.GetEncoding(name)
+			if err != nil {
+				Log("Failed to load tokenizer", name, err)
+			}
+			t.encoding = encoding
+			close(t.ready)
+		}(getEncodingName(model))
+	})
+
+	select {
+	case <-t.ready:
+		return t.encoding
+	default:
+		return nil
+	}
+}
+
+// WaitForTokenizer blocks until the tokenizer of the model is loaded
+func WaitForTokenizer(model string) {
+	getTokenizer(model)
+	<-tokenizers[getEncodingName(model)].ready
+}
+
+// CountTokens counts tokens of a text with the tokenizer of the model.
+// While the tokenizer is loading, the count is estimated
+func CountTokens(text string, model string) int {
+	if text == "" {
+		return 0
+	}
+
+	encoding := getTokenizer(model)
+	if encoding == nil {
+		return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+	}
+
+	return len(encoding.EncodeOrdinary(text))
+}
+
+func CountMessageTokens(message MessageToSend, model string) int {
+	tokens := messageTokensOverhead + CountTokens(message.Content, model)
+	for _, toolCall := range message.ToolCalls {
+		tokens += CountTokens(toolCall.Function.Name, model) + CountTokens(toolCall.Function.Arguments, model)
+	}
+	return tokens
+}
+
+func CountMessagesTokens(messages []MessageToSend, model string) int {
+	tokens := replyTokensOverhead
+	for _, message := range messages {
+		tokens += CountMessageTokens(message, model)
+	}
+	return tokens
+}
+
+// GetContextLimit returns the context window of a model or 0 if it is unknown.
+// Limits from the config take precedence over the built-in ones
+func GetContextLimit(model string, overrides map[string]int) int {
+	if limit, ok := overrides[model]; ok {
+		return limit
+	}
+
+	// gpt-4.5 must not get the window of gpt-4, whatever the order of the entries
+	match := contextLimit{}
+	for _, limit := range defaultContextLimits {
+		if strings.HasPrefix(model, limit.modelPrefix) && len(limit.modelPrefix) > len(match.modelPrefix) {
+			match = limit
+		}
+	}
+
+	return match.tokens
+}
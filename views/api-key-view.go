@@ -0,0 +1,142 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/secrets"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	passphraseStep = iota
+	apiKeyStep
+)
+
+// ApiKeyView is shown before the main view when the api key is not stored yet,
+// or when the keys file has to be unlocked with a passphrase
+type ApiKeyView struct {
+	profile       string
+	store         string
+	askKey        bool
+	askPassphrase bool
+	step          int
+	input         textinput.Model
+	colors        util.SchemeColors
+
+	Passphrase string
+	ApiKey     string
+	Cancelled  bool
+}
+
+func NewApiKeyView(cfg config.Config, askKey bool, askPassphrase bool) ApiKeyView {
+	step := apiKeyStep
+	if askPassphrase {
+		step = passphraseStep
+	}
+
+	view := ApiKeyView{
+		profile:       secrets.GetProfile(cfg),
+		store:         secrets.GetStore(cfg),
+		askKey:        askKey,
+		askPassphrase: askPassphrase,
+		step:          step,
+		colors:        cfg.ColorScheme.GetColors(),
+	}
+	view.input = view.newInput()
+	return view
+}
+
+// RunApiKeyPrompt blocks until the user fills in the inputs or quits
+func RunApiKeyPrompt(cfg config.Config, askKey bool, askPassphrase bool) (ApiKeyView, error) {
+	model, err := tea.NewProgram(NewApiKeyView(cfg, askKey, askPassphrase), tea.WithAltScreen()).Run()
+	if err != nil {
+		return ApiKeyView{}, err
+	}
+
+	return model.(ApiKeyView), nil
+}
+
+func (v ApiKeyView) newInput() textinput.Model {
+	input := textinput.New()
+	input.EchoMode = textinput.EchoPassword
+	input.EchoCharacter = '•'
+	input.Prompt = "> "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(v.colors.AccentColor)
+	input.Focus()
+
+	if v.step == passphraseStep {
+		input.Placeholder = "passphrase of the keys file"
+	} else {
+		input.Placeholder = "api key"
+	}
+	return input
+}
+
+func (v ApiKeyView) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (v ApiKeyView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			v.Cancelled = true
+			return v, tea.Quit
+
+		case tea.KeyEnter:
+			value := v.input.Value()
+			if value == "" {
+				return v, nil
+			}
+
+			if v.step == passphraseStep {
+				v.Passphrase = value
+				// only the passphrase is needed to read an already stored key
+				if !v.askKey {
+					return v, tea.Quit
+				}
+				v.step = apiKeyStep
+				v.input = v.newInput()
+				return v, textinput.Blink
+			}
+
+			v.ApiKey = value
+			return v, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	v.input, cmd = v.input.Update(msg)
+	return v, cmd
+}
+
+func (v ApiKeyView) View() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(v.colors.MainColor)
+	muted := lipgloss.NewStyle().Foreground(v.colors.NormalTabBorderColor)
+
+	heading := fmt.Sprintf("Api key for %s", v.profile)
+	description := fmt.Sprintf("The key will be saved to the %s store and asked only once.", v.store)
+	if v.store == secrets.FileStore {
+		description += "\nIt is encrypted with the passphrase, set NEKOT_KEYS_PASSPHRASE to skip this prompt."
+	}
+	if v.step == passphraseStep {
+		heading = "Passphrase of the keys file"
+	}
+
+	return lipgloss.NewStyle().Padding(1, 2).Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			title.Render(heading),
+			muted.Render(description),
+			"",
+			v.input.View(),
+			"",
+			muted.Render("enter to confirm, esc to quit"),
+		),
+	)
+}
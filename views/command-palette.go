@@ -0,0 +1,186 @@
+package views
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/tearingItUp786/nekot/components"
+	"github.com/tearingItUp786/nekot/sessions"
+	"github.com/tearingItUp786/nekot/snippets"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const (
+	newSessionCommand  = "newSession"
+	switchModelCommand = "switchModel"
+	changeThemeCommand = "changeTheme"
+	regenerateCommand  = "regenerate"
+	copyLastCommand    = "copyLast"
+	copyAllCommand     = "copyAll"
+	exportMdCommand    = "exportMarkdown"
+	exportJsonCommand  = "exportJson"
+	zenModeCommand     = "zenMode"
+	editorModeCommand  = "editorMode"
+	showHelpCommand    = "showHelp"
+	snippetCommand     = "insertSnippet"
+	copyUsageCommand   = "copyUsageReport"
+)
+
+// paletteMode tells what the palette lists, it is also a picker of snippets and sent prompts
+type paletteMode int
+
+const (
+	commandsPalette paletteMode = iota
+	snippetsPicker
+	historyPicker
+)
+
+const (
+	paletteTitleHeight  = 2
+	paletteBorderHeight = 2
+)
+
+// getPaletteCommands leaves out the commands that can't run right now,
+// the panes ignore session and settings changes during the inference
+func (m MainView) getPaletteCommands() []components.Command {
+	isIdle := m.sessionOrchestrator.ProcessingMode != sessions.PROCESSING
+	canFocusSettings := util.IsFocusAllowed(util.NormalMode, util.SettingsPane, m.terminalWidth)
+
+	commands := []components.Command{}
+	if isIdle {
+		commands = append(commands, components.Command{ID: newSessionCommand, Title: "New session"})
+	}
+	if isIdle && canFocusSettings {
+		commands = append(commands,
+			components.Command{ID: switchModelCommand, Title: "Switch model"},
+			components.Command{ID: changeThemeCommand, Title: "Change theme"})
+	}
+	if isIdle {
+		commands = append(commands,
+			components.Command{ID: regenerateCommand, Title: "Regenerate last answer"},
+			components.Command{ID: snippetCommand, Title: "Insert snippet"},
+			components.Command{ID: exportMdCommand, Title: "Export session as Markdown"},
+			components.Command{ID: exportJsonCommand, Title: "Export session as JSON"})
+	}
+
+	return append(commands,
+		components.Command{ID: copyLastCommand, Title: "Copy last answer"},
+		components.Command{ID: copyAllCommand, Title: "Copy whole session"},
+		components.Command{ID: copyUsageCommand, Title: "Copy usage report"},
+		components.Command{ID: zenModeCommand, Title: "Toggle zen mode", Key: m.keys.zenMode.Help().Key},
+		components.Command{ID: editorModeCommand, Title: "Toggle editor mode", Key: m.keys.editorMode.Help().Key},
+		components.Command{ID: showHelpCommand, Title: "Show key bindings", Key: m.keys.help.Help().Key},
+	)
+}
+
+func (m MainView) canOpenPalette() bool {
+	return m.viewReady && !m.showHelp && !m.showSnippetForm && !m.promptPane.IsAwaitingToolConfirmation()
+}
+
+func (m *MainView) openPalette() tea.Cmd {
+	m.palette = components.NewCommandPalette(m.getPaletteCommands(), m.getColors())
+	m.paletteMode = commandsPalette
+	m.showPalette = true
+	return m.palette.Init()
+}
+
+func (m MainView) handlePaletteKeys(msg tea.KeyMsg) (MainView, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch {
+	case m.palette.IsCloseKey(msg), key.Matches(msg, m.keys.commandPalette):
+		m.showPalette = false
+
+	case m.palette.IsRunKey(msg):
+		m.showPalette = false
+		command, ok := m.palette.GetSelectedCommand()
+		if !ok {
+			break
+		}
+
+		switch m.paletteMode {
+		case snippetsPicker:
+			cmd = m.useSnippet(command.ID)
+		case historyPicker:
+			cmd = m.restorePrompt(command.ID)
+		default:
+			cmd = m.runCommand(command.ID)
+		}
+
+	default:
+		m.palette, cmd = m.palette.Update(msg)
+	}
+
+	return m, cmd
+}
+
+// runCommand dispatches the same messages as the keys of the action, so the panes handle both alike
+func (m *MainView) runCommand(id string) tea.Cmd {
+	switch id {
+	case newSessionCommand:
+		return util.SendNewSessionMsg
+
+	// pickers are part of the settings pane and only take keys while it is focused
+	case switchModelCommand:
+		return tea.Sequence(m.focusPane(util.SettingsPane), util.SendPickModelMsg)
+	case changeThemeCommand:
+		return tea.Sequence(m.focusPane(util.SettingsPane), util.SendPickThemeMsg)
+
+	case regenerateCommand:
+		return util.SendRegenerateMsg
+	case snippetCommand:
+		return snippets.SendInsertSnippetRequestedMsg("")
+	case copyLastCommand:
+		return util.SendCopyLastMsg
+	case copyAllCommand:
+		return util.SendCopyAllMsgs
+	case copyUsageCommand:
+		return util.SendCopyUsageReportMsg
+	case exportMdCommand:
+		return sessions.SendExportSessionRequestedMsg(sessions.MarkdownExport)
+	case exportJsonCommand:
+		return sessions.SendExportSessionRequestedMsg(sessions.JsonExport)
+
+	case zenModeCommand:
+		return m.toggleZenMode()
+	case editorModeCommand:
+		cmd := m.focusPane(util.PromptPane)
+		return tea.Batch(cmd, m.toggleEditorMode())
+	case showHelpCommand:
+		m.showHelp = true
+	}
+
+	return nil
+}
+
+// paletteView takes the place of the chat pane, the same way as the help overlay
+func (m MainView) paletteView() string {
+	colors := m.getColors()
+	w, h := util.CalcChatPaneSize(m.terminalWidth, m.terminalHeight-helpFooterHeight, m.viewMode)
+
+	palette := m.palette
+	palette.SetSize(w-2, h-paletteTitleHeight)
+
+	title := "Commands"
+	switch m.paletteMode {
+	case snippetsPicker:
+		title = "Snippets"
+	case historyPicker:
+		title = "Prompt history"
+	}
+	titleView := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(colors.MainColor).
+		Render(title)
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.ActiveTabBorderColor).
+		MarginRight(util.ChatPaneMarginRight).
+		Padding(0, 1).
+		Width(w).
+		Height(h).
+		MaxHeight(h + paletteBorderHeight).
+		Render(lipgloss.JoinVertical(lipgloss.Left, titleView, "", palette.View()))
+}
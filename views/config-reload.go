@@ -0,0 +1,42 @@
+package views
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/secrets"
+)
+
+// reloadConfig loads the api key again when the reloaded config takes it from elsewhere,
+// otherwise the key of the previous provider would be sent to the new one
+func reloadConfig(path string, previous config.Config) tea.Cmd {
+	reload := config.ReloadConfig(path)
+
+	return func() tea.Msg {
+		msg := reload()
+		changed, ok := msg.(config.ConfigChanged)
+		if !ok || !isApiKeySourceChanged(previous, changed.Config) || changed.Config.Request.Auth.Scheme == config.NoAuth {
+			return msg
+		}
+
+		// asking for a passphrase or a key needs the prompt shown on start
+		apiKey, err := secrets.ResolveApiKey(changed.Config, secrets.GetPassphraseFromEnv())
+		if err != nil {
+			return config.ConfigReloadFailed{
+				Err: fmt.Errorf("api key for %s could not be loaded, restart to enter it: %w", secrets.GetProfile(changed.Config), err),
+			}
+		}
+
+		secrets.SetApiKey(apiKey)
+		return msg
+	}
+}
+
+func isApiKeySourceChanged(previous, current config.Config) bool {
+	return secrets.GetProfile(previous) != secrets.GetProfile(current) ||
+		previous.ApiKeyCommand != current.ApiKeyCommand ||
+		previous.ApiKeyStore != current.ApiKeyStore ||
+		previous.Request.Auth.Scheme != current.Request.Auth.Scheme
+}
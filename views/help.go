@@ -0,0 +1,185 @@
+package views
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/tearingItUp786/nekot/config"
+	"github.com/tearingItUp786/nekot/sessions"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const helpFooterHeight = 1
+
+var helpCloseKey = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close help"))
+
+// the prompt input completes slash commands with tab
+var slashCompleteKey = key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete"))
+
+// helpKeyMap lists bindings of the focused pane first, followed by the global ones that apply
+type helpKeyMap struct {
+	pane   []key.Binding
+	global []key.Binding
+	help   key.Binding
+}
+
+func (k helpKeyMap) ShortHelp() []key.Binding {
+	return append([]key.Binding{k.help}, k.pane...)
+}
+
+func (k helpKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.pane, k.global}
+}
+
+func (m MainView) getHelpKeyMap() helpKeyMap {
+	keys := helpKeyMap{help: m.keys.help}
+
+	if m.showPalette {
+		keys.pane = m.palette.HelpKeys()
+		keys.global = []key.Binding{m.keys.quit}
+		return keys
+	}
+	if m.showSnippetForm {
+		keys.pane = m.snippetForm.HelpKeys()
+		keys.global = []key.Binding{m.keys.quit}
+		return keys
+	}
+
+	switch m.focused {
+	case util.PromptPane:
+		keys.pane = m.promptPane.HelpKeys()
+	case util.ChatPane:
+		keys.pane = m.chatPane.HelpKeys()
+	case util.SettingsPane:
+		keys.pane = m.settingsPane.HelpKeys()
+	case util.SessionsPane:
+		keys.pane = m.sessionsPane.HelpKeys()
+	}
+
+	if m.sessionOrchestrator.ProcessingMode == sessions.PROCESSING {
+		keys.global = append(keys.global, m.keys.cancel)
+	}
+	if m.focused == util.PromptPane {
+		keys.global = append(keys.global, m.keys.editorMode)
+	}
+	if m.viewMode == util.NormalMode {
+		keys.global = append(keys.global, m.keys.nextPane, m.keys.jumpToPane)
+	}
+	keys.global = append(keys.global, m.keys.commandPalette, m.keys.zenMode, m.keys.quit)
+
+	return keys
+}
+
+// getHelpTitle names the focused pane and the mode it is in, since both change the available keys
+func (m MainView) getHelpTitle() string {
+	title := "Help: "
+	switch m.focused {
+	case util.PromptPane:
+		title += "prompt"
+	case util.ChatPane:
+		title += "chat"
+	case util.SettingsPane:
+		title += "settings"
+	case util.SessionsPane:
+		title += "sessions"
+	case util.UsagePane:
+		title += "usage"
+	}
+
+	switch {
+	case m.promptPane.IsAwaitingToolConfirmation():
+		title += ", tool call confirmation"
+	case m.focused == util.ChatPane && m.chatPane.IsSelectionMode():
+		title += ", selection mode"
+	case m.focused == util.PromptPane && m.promptPane.IsTypingInProcess():
+		title += ", insert mode"
+	}
+
+	switch m.viewMode {
+	case util.ZenMode:
+		title += ", zen mode"
+	case util.TextEditMode:
+		title += ", editor mode"
+	}
+
+	return title
+}
+
+func (m MainView) newHelpModel(colors util.SchemeColors) help.Model {
+	model := help.New()
+	model.Width = m.terminalWidth
+	model.ShortSeparator = " • "
+	model.Styles.ShortKey = lipgloss.NewStyle().Foreground(colors.AccentColor)
+	model.Styles.ShortDesc = lipgloss.NewStyle().Foreground(colors.NormalTabBorderColor)
+	model.Styles.ShortSeparator = lipgloss.NewStyle().Foreground(colors.NormalTabBorderColor)
+	model.Styles.Ellipsis = lipgloss.NewStyle().Foreground(colors.NormalTabBorderColor)
+	model.Styles.FullKey = lipgloss.NewStyle().Foreground(colors.AccentColor)
+	model.Styles.FullDesc = lipgloss.NewStyle().Foreground(colors.DefaultTextColor)
+	model.Styles.FullSeparator = lipgloss.NewStyle().Foreground(colors.NormalTabBorderColor)
+	return model
+}
+
+func (m MainView) getColors() util.SchemeColors {
+	cfg, ok := config.FromContext(m.context)
+	if !ok {
+		return util.OriginalPink.GetColors()
+	}
+	return cfg.ColorScheme.GetColors()
+}
+
+// helpView takes the place of the chat pane, so that the focused pane stays visible next to it
+func (m MainView) helpView() string {
+	colors := m.getColors()
+	w, h := util.CalcChatPaneSize(m.terminalWidth, m.terminalHeight-helpFooterHeight, m.viewMode)
+
+	helpModel := m.newHelpModel(colors)
+	helpModel.ShowAll = true
+	keys := m.getHelpKeyMap()
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(colors.MainColor).
+		Render(m.getHelpTitle())
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.ActiveTabBorderColor).
+		MarginRight(util.ChatPaneMarginRight).
+		Padding(0, 1).
+		Width(w).
+		Height(h).
+		MaxHeight(h + 2).
+		Render(lipgloss.JoinVertical(lipgloss.Left,
+			title,
+			"",
+			helpModel.View(keys),
+			"",
+			helpModel.ShortHelpView([]key.Binding{m.keys.help, helpCloseKey}),
+		))
+}
+
+// helpFooterView lists the slash commands matching the prompt while one is typed, the keys otherwise
+func (m MainView) helpFooterView() string {
+	helpModel := m.newHelpModel(m.getColors())
+	helpModel.Width = m.terminalWidth - 1
+
+	content := helpModel.View(m.getHelpKeyMap())
+	if suggestions := m.promptPane.SlashSuggestions(); len(suggestions) > 0 && !m.showPalette && !m.showSnippetForm {
+		bindings := []key.Binding{}
+		if m.viewMode != util.TextEditMode {
+			bindings = append(bindings, slashCompleteKey)
+		}
+		for _, suggestion := range suggestions {
+			bindings = append(bindings, key.NewBinding(
+				key.WithKeys(suggestion.Usage),
+				key.WithHelp(suggestion.Usage, suggestion.Description)))
+		}
+		content = helpModel.ShortHelpView(bindings)
+	}
+
+	return lipgloss.NewStyle().
+		PaddingLeft(1).
+		MaxWidth(m.terminalWidth).
+		Render(content)
+}
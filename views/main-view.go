@@ -14,31 +14,56 @@ import (
 	"golang.org/x/term"
 
 	"github.com/tearingItUp786/nekot/clients"
+	"github.com/tearingItUp786/nekot/components"
+	"github.com/tearingItUp786/nekot/config"
 	"github.com/tearingItUp786/nekot/panes"
 	"github.com/tearingItUp786/nekot/sessions"
+	"github.com/tearingItUp786/nekot/settings"
+	"github.com/tearingItUp786/nekot/snippets"
 	"github.com/tearingItUp786/nekot/util"
 )
 
 const pulsarIntervalMs = 300
+const configPulsarInterval = time.Second
 
 var asyncDeps = []util.AsyncDependency{util.SettingsPaneModule, util.Orchestrator}
 
 type keyMap struct {
-	cancel     key.Binding
-	zenMode    key.Binding
-	editorMode key.Binding
-	nextPane   key.Binding
-	jumpToPane key.Binding
-	quit       key.Binding
+	cancel         key.Binding
+	zenMode        key.Binding
+	editorMode     key.Binding
+	nextPane       key.Binding
+	jumpToPane     key.Binding
+	quit           key.Binding
+	help           key.Binding
+	commandPalette key.Binding
 }
 
 var defaultKeyMap = keyMap{
-	cancel:     key.NewBinding(key.WithKeys("ctrl+s", "ctrl+b"), key.WithHelp("ctrl+b/ctrl+s", "stop inference")),
-	zenMode:    key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "activate/deactivate zen mode")),
-	editorMode: key.NewBinding(key.WithKeys("ctrl+e"), key.WithHelp("ctrl+e", "enter/exit editor mode")),
-	quit:       key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit app")),
-	jumpToPane: key.NewBinding(key.WithKeys("1", "2", "3", "4"), key.WithHelp("1,2,3,4", "jump to specific pane")),
-	nextPane:   key.NewBinding(key.WithKeys(tea.KeyTab.String()), key.WithHelp("TAB", "move to next pane")),
+	cancel:         key.NewBinding(key.WithKeys("ctrl+b"), key.WithHelp("ctrl+b", "stop inference")),
+	zenMode:        key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "activate/deactivate zen mode")),
+	editorMode:     key.NewBinding(key.WithKeys("ctrl+e"), key.WithHelp("ctrl+e", "enter/exit editor mode")),
+	quit:           key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit app")),
+	jumpToPane:     key.NewBinding(key.WithKeys("1", "2", "3", "4", "5"), key.WithHelp("1,2,3,4,5", "jump to specific pane")),
+	nextPane:       key.NewBinding(key.WithKeys(tea.KeyTab.String()), key.WithHelp("TAB", "move to next pane")),
+	help:           key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "show/hide help")),
+	commandPalette: key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "open command palette")),
+}
+
+// panes in the order of the jumpToPane keys
+var jumpTargets = []util.Pane{util.PromptPane, util.ChatPane, util.SettingsPane, util.SessionsPane, util.UsagePane}
+
+func init() {
+	util.RegisterKeyBindings(util.GlobalKeys, util.KeyBindings{
+		"cancel":         &defaultKeyMap.cancel,
+		"zenMode":        &defaultKeyMap.zenMode,
+		"editorMode":     &defaultKeyMap.editorMode,
+		"nextPane":       &defaultKeyMap.nextPane,
+		"jumpToPane":     &defaultKeyMap.jumpToPane,
+		"quit":           &defaultKeyMap.quit,
+		"help":           &defaultKeyMap.help,
+		"commandPalette": &defaultKeyMap.commandPalette,
+	})
 }
 
 type MainView struct {
@@ -48,15 +73,25 @@ type MainView struct {
 	error            util.ErrorEvent
 	currentSessionID string
 	keys             keyMap
+	showHelp         bool
+	showPalette      bool
+	palette          components.CommandPalette
+	paletteMode      paletteMode
+	showSnippetForm  bool
+	snippetForm      components.SnippetForm
 
 	chatPane     panes.ChatPane
 	promptPane   panes.PromptPane
 	sessionsPane panes.SessionsPane
 	settingsPane panes.SettingsPane
 	infoPane     panes.InfoPane
+	usagePane    panes.UsagePane
 	loadedDeps   []util.AsyncDependency
 
 	sessionOrchestrator sessions.Orchestrator
+	snippetsService     *snippets.SnippetsService
+	configPath          string
+	configModTime       time.Time
 	context             context.Context
 	completionContext   context.Context
 	cancelInference     context.CancelFunc
@@ -76,27 +111,37 @@ func dimensionsPulsar() tea.Msg {
 }
 
 func NewMainView(db *sql.DB, ctx context.Context) MainView {
-	promptPane := panes.NewPromptPane(ctx)
+	promptPane := panes.NewPromptPane(db, ctx)
 	sessionsPane := panes.NewSessionsPane(db, ctx)
 	settingsPane := panes.NewSettingsPane(db, ctx)
 	statusBarPane := panes.NewInfoPane(db, ctx)
+	usagePane := panes.NewUsagePane(db, ctx)
 
 	w, h := util.CalcChatPaneSize(util.DefaultTerminalWidth, util.DefaultTerminalHeight, util.NormalMode)
 	chatPane := panes.NewChatPane(ctx, w, h)
 
 	orchestrator := sessions.NewOrchestrator(db, ctx)
 
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		util.Log("Config file won't be watched:", err)
+	}
+
 	return MainView{
 		keys:                defaultKeyMap,
 		viewMode:            util.NormalMode,
 		focused:             util.PromptPane,
 		currentSessionID:    "",
 		sessionOrchestrator: orchestrator,
+		snippetsService:     snippets.NewSnippetsService(db),
 		promptPane:          promptPane,
 		sessionsPane:        sessionsPane,
 		settingsPane:        settingsPane,
 		infoPane:            statusBarPane,
+		usagePane:           usagePane,
 		chatPane:            chatPane,
+		configPath:          configPath,
+		configModTime:       config.GetConfigModTime(configPath),
 		context:             ctx,
 	}
 }
@@ -110,6 +155,7 @@ func (m MainView) Init() tea.Cmd {
 		m.settingsPane.Init(),
 		m.sessionsPane.Init(),
 		func() tea.Msg { return dimensionsPulsar() },
+		m.watchConfig(),
 	)
 }
 
@@ -119,20 +165,58 @@ func (m MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds []tea.Cmd
 	)
 
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.terminalWidth = size.Width
+		m.terminalHeight = size.Height
+		// panes share the screen with the help footer
+		size.Height -= helpFooterHeight
+		msg = size
+	}
+
+	// the help overlay takes all keys, except for quitting
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.showHelp && !key.Matches(keyMsg, m.keys.quit) {
+		if key.Matches(keyMsg, m.keys.help, helpCloseKey) {
+			m.showHelp = false
+		}
+		return m, nil
+	}
+
+	// the palette and the snippet form take all keys as well, the palette key reaches it before the prompt input does
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && !key.Matches(keyMsg, m.keys.quit) {
+		if m.showPalette {
+			return m.handlePaletteKeys(keyMsg)
+		}
+		if m.showSnippetForm {
+			return m.handleSnippetFormKeys(keyMsg)
+		}
+		if key.Matches(keyMsg, m.keys.commandPalette) && m.canOpenPalette() {
+			return m, m.openPalette()
+		}
+	}
+
 	m.sessionOrchestrator, cmd = m.sessionOrchestrator.Update(msg)
 	cmds = append(cmds, cmd)
 
 	m.infoPane, cmd = m.infoPane.Update(msg)
 	cmds = append(cmds, cmd)
 
+	m.usagePane, cmd = m.usagePane.Update(msg)
+	cmds = append(cmds, cmd)
+
 	m.promptPane, cmd = m.promptPane.Update(msg)
 	cmds = append(cmds, cmd)
 
-	if m.sessionOrchestrator.ProcessingMode == sessions.IDLE {
+	// config changes can't wait for the inference to finish, otherwise they'd be lost.
+	// Neither can the settings reloaded for them, the settings pane shows a spinner until they arrive
+	_, isConfigChanged := msg.(config.ConfigChanged)
+	if m.sessionOrchestrator.ProcessingMode == sessions.IDLE || isConfigChanged {
 		m.sessionsPane, cmd = m.sessionsPane.Update(msg)
 		cmds = append(cmds, cmd)
 		m.settingsPane, cmd = m.settingsPane.Update(msg)
 		cmds = append(cmds, cmd)
+	} else if isSettingsReloadResult(msg) {
+		m.settingsPane, cmd = m.settingsPane.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	switch msg := msg.(type) {
@@ -146,6 +230,17 @@ func (m MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, dimensionsPulsar)
 		}
 
+	case config.ConfigFileChecked:
+		if !msg.ModTime.IsZero() && !msg.ModTime.Equal(m.configModTime) {
+			m.configModTime = msg.ModTime
+			cfg, _ := config.FromContext(m.context)
+			cmds = append(cmds, reloadConfig(m.configPath, *cfg))
+		}
+		cmds = append(cmds, m.watchConfig())
+
+	case config.ConfigChanged:
+		m.context = config.WithConfig(m.context, &msg.Config)
+
 	case util.ViewModeChanged:
 		m.viewMode = msg.Mode
 
@@ -166,19 +261,47 @@ func (m MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewReady = true
 		cmds = append(cmds, util.SendProcessingStateChangedMsg(false))
 
+	case util.RegenerateMsg:
+		if m.sessionOrchestrator.ProcessingMode == sessions.PROCESSING {
+			break
+		}
+		if err := m.sessionOrchestrator.RemoveLastAnswer(); err != nil {
+			return m, util.MakeErrorMsg(err.Error())
+		}
+		m.error = util.ErrorEvent{}
+		return m, m.startCompletion()
+
+	case util.PromptHistorySearchRequested:
+		return m, m.openHistoryPicker(msg.Prompts)
+
+	case snippets.InsertSnippetRequested:
+		if msg.Name == "" {
+			return m, m.openSnippetsPicker()
+		}
+		return m, m.useSnippet(msg.Name)
+
+	case snippets.SaveSnippetRequested:
+		cmds = append(cmds, m.saveSnippet(msg))
+
+	case snippets.DeleteSnippetRequested:
+		cmds = append(cmds, m.deleteSnippet(msg))
+
 	case util.PromptReady:
 		m.error = util.ErrorEvent{}
 		m.sessionOrchestrator.ArrayOfMessages = append(m.sessionOrchestrator.ArrayOfMessages, clients.ConstructUserMessage(msg.Prompt))
-		m.sessionOrchestrator.ProcessingMode = sessions.PROCESSING
-		m.viewMode = util.NormalMode
+		return m, m.startCompletion()
+
+	case sessions.ToolCallsRequested:
+		m.focused = util.PromptPane
+		cmds = append(cmds, m.resetFocus())
 
-		completionContext, cancelInference := context.WithCancel(m.context)
-		m.completionContext = completionContext
-		m.cancelInference = cancelInference
-		return m, tea.Batch(
-			util.SendProcessingStateChangedMsg(true),
-			m.chatPane.DisplayCompletion(m.completionContext, m.sessionOrchestrator),
-			util.SendViewModeChangedMsg(m.viewMode))
+	case sessions.ToolResultsReady:
+		// orchestrator has already appended the tool results to the messages,
+		// when they couldn't be saved its error is shown instead of continuing
+		if m.sessionOrchestrator.ProcessingMode == sessions.ERROR {
+			return m, tea.Batch(cmds...)
+		}
+		return m, tea.Batch(append(cmds, m.startCompletion())...)
 
 	case tea.KeyMsg:
 		if !m.viewReady {
@@ -193,56 +316,29 @@ func (m MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, m.keys.zenMode):
-			m.focused = util.PromptPane
-			m.sessionsPane, _ = m.sessionsPane.Update(util.MakeFocusMsg(m.focused == util.SessionsPane))
-			m.settingsPane, _ = m.settingsPane.Update(util.MakeFocusMsg(m.focused == util.SettingsPane))
-
-			cmds = append(cmds, cmd)
-
-			switch m.viewMode {
-			case util.NormalMode:
-				m.viewMode = util.ZenMode
-			case util.ZenMode:
-				m.viewMode = util.NormalMode
-			}
-
-			cmds = append(cmds, util.SendViewModeChangedMsg(m.viewMode))
+			cmds = append(cmds, m.toggleZenMode())
 
 		case key.Matches(msg, m.keys.editorMode):
 			if m.focused != util.PromptPane {
 				break
 			}
-
-			switch m.viewMode {
-			case util.NormalMode:
-				m.viewMode = util.TextEditMode
-			case util.ZenMode:
-				m.viewMode = util.TextEditMode
-			case util.TextEditMode:
-				m.viewMode = util.NormalMode
-			}
-			cmds = append(cmds, util.SendViewModeChangedMsg(m.viewMode))
+			cmds = append(cmds, m.toggleEditorMode())
 
 		case key.Matches(msg, m.keys.jumpToPane):
 			if !m.isFocusChangeAllowed() {
 				break
 			}
 
-			var targetPane util.Pane
-			switch msg.String() {
-			case "1":
-				targetPane = util.PromptPane
-			case "2":
-				targetPane = util.ChatPane
-			case "3":
-				targetPane = util.SettingsPane
-			case "4":
-				targetPane = util.SessionsPane
+			// n-th key of the binding jumps to the n-th pane, so that the keys can be overridden
+			targetIdx := slices.Index(m.keys.jumpToPane.Keys(), msg.String())
+			if targetIdx < 0 || targetIdx >= len(jumpTargets) {
+				break
 			}
 
+			targetPane := jumpTargets[targetIdx]
 			if util.IsFocusAllowed(m.viewMode, targetPane, m.terminalWidth) {
 				m.focused = targetPane
-				m.resetFocus()
+				cmds = append(cmds, m.resetFocus())
 			}
 
 		case key.Matches(msg, m.keys.nextPane):
@@ -251,16 +347,20 @@ func (m MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m.focused = util.GetNewFocusMode(m.viewMode, m.focused, m.terminalWidth)
-			m.resetFocus()
+			cmds = append(cmds, m.resetFocus())
 
 		case key.Matches(msg, m.keys.quit):
+			m.promptPane.SaveDraft()
+			m.sessionOrchestrator.Shutdown()
 			return m, tea.Quit
+
+		case key.Matches(msg, m.keys.help):
+			if !m.isTextInputActive() {
+				m.showHelp = true
+			}
 		}
 
 	case tea.WindowSizeMsg:
-		m.terminalWidth = msg.Width
-		m.terminalHeight = msg.Height
-
 		m.chatPane, cmd = m.chatPane.Update(msg)
 		cmds = append(cmds, cmd)
 		m.settingsPane, cmd = m.settingsPane.Update(msg)
@@ -269,6 +369,16 @@ func (m MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 	}
 
+	// keys are handled above, the palette and the form only need the cursor blinks here
+	if m.showPalette {
+		m.palette, cmd = m.palette.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.showSnippetForm {
+		m.snippetForm, cmd = m.snippetForm.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
 	m.chatPane, cmd = m.chatPane.Update(msg)
 	cmds = append(cmds, cmd)
 	return m, tea.Batch(cmds...)
@@ -288,6 +398,18 @@ func (m MainView) View() string {
 	if m.error.Message != "" {
 		mainView = m.chatPane.DisplayError(m.error.Message)
 	}
+	if m.focused == util.UsagePane {
+		mainView = m.usagePane.View()
+	}
+	if m.showHelp {
+		mainView = m.helpView()
+	}
+	if m.showPalette {
+		mainView = m.paletteView()
+	}
+	if m.showSnippetForm {
+		mainView = m.snippetFormView()
+	}
 
 	secondaryScreen := ""
 	if m.viewMode == util.NormalMode {
@@ -311,20 +433,113 @@ func (m MainView) View() string {
 			lipgloss.Left,
 			windowViews,
 			promptView,
+			m.helpFooterView(),
 		),
 	)
 }
 
-func (m *MainView) resetFocus() {
+func (m MainView) watchConfig() tea.Cmd {
+	if m.configPath == "" {
+		return nil
+	}
+	return config.CheckConfigFile(m.configPath, configPulsarInterval)
+}
+
+func (m *MainView) startCompletion() tea.Cmd {
+	m.sessionOrchestrator.ProcessingMode = sessions.PROCESSING
+	m.viewMode = util.NormalMode
+
+	completionContext, cancelInference := context.WithCancel(m.context)
+	m.completionContext = completionContext
+	m.cancelInference = cancelInference
+	return tea.Batch(
+		util.SendProcessingStateChangedMsg(true),
+		m.chatPane.DisplayCompletion(m.completionContext, m.sessionOrchestrator),
+		util.SendViewModeChangedMsg(m.viewMode))
+}
+
+func (m *MainView) toggleZenMode() tea.Cmd {
+	m.focused = util.PromptPane
+	m.sessionsPane, _ = m.sessionsPane.Update(util.MakeFocusMsg(m.focused == util.SessionsPane))
+	m.settingsPane, _ = m.settingsPane.Update(util.MakeFocusMsg(m.focused == util.SettingsPane))
+
+	switch m.viewMode {
+	case util.NormalMode:
+		m.viewMode = util.ZenMode
+	case util.ZenMode:
+		m.viewMode = util.NormalMode
+	}
+
+	return util.SendViewModeChangedMsg(m.viewMode)
+}
+
+func (m *MainView) toggleEditorMode() tea.Cmd {
+	switch m.viewMode {
+	case util.NormalMode:
+		m.viewMode = util.TextEditMode
+	case util.ZenMode:
+		m.viewMode = util.TextEditMode
+	case util.TextEditMode:
+		m.viewMode = util.NormalMode
+	}
+	return util.SendViewModeChangedMsg(m.viewMode)
+}
+
+// openEditor focuses the prompt in editor mode, its view mode message has to reach the prompt pane
+// before any text is put into the editor
+func (m *MainView) openEditor() tea.Cmd {
+	m.focused = util.PromptPane
+	focusCmd := m.resetFocus()
+
+	if m.viewMode == util.TextEditMode {
+		return focusCmd
+	}
+	m.viewMode = util.TextEditMode
+	return tea.Batch(focusCmd, util.SendViewModeChangedMsg(m.viewMode))
+}
+
+// focusPane leaves zen and editor modes when the pane is hidden in them
+func (m *MainView) focusPane(pane util.Pane) tea.Cmd {
+	var cmd tea.Cmd
+	if !util.IsFocusAllowed(m.viewMode, pane, m.terminalWidth) {
+		m.viewMode = util.NormalMode
+		cmd = util.SendViewModeChangedMsg(m.viewMode)
+	}
+
+	m.focused = pane
+	return tea.Batch(cmd, m.resetFocus())
+}
+
+func (m *MainView) resetFocus() tea.Cmd {
 	m.sessionsPane, _ = m.sessionsPane.Update(util.MakeFocusMsg(m.focused == util.SessionsPane))
 	m.settingsPane, _ = m.settingsPane.Update(util.MakeFocusMsg(m.focused == util.SettingsPane))
 	m.chatPane, _ = m.chatPane.Update(util.MakeFocusMsg(m.focused == util.ChatPane))
 	m.promptPane, _ = m.promptPane.Update(util.MakeFocusMsg(m.focused == util.PromptPane))
+
+	var cmd tea.Cmd
+	m.usagePane, cmd = m.usagePane.Update(util.MakeFocusMsg(m.focused == util.UsagePane))
+	return cmd
+}
+
+// isTextInputActive is true while keys are typed into an input, instead of triggering actions
+func (m MainView) isTextInputActive() bool {
+	return m.promptPane.IsTypingInProcess() ||
+		!m.settingsPane.AllowFocusChange() ||
+		!m.sessionsPane.AllowFocusChange()
+}
+
+func isSettingsReloadResult(msg tea.Msg) bool {
+	switch msg.(type) {
+	case settings.UpdateSettingsEvent, util.ErrorEvent:
+		return true
+	}
+	return false
 }
 
 // TODO: use event to lock/unlock allowFocusChange flag
 func (m MainView) isFocusChangeAllowed() bool {
 	if m.promptPane.IsTypingInProcess() ||
+		m.promptPane.IsAwaitingToolConfirmation() ||
 		!m.chatPane.AllowFocusChange() ||
 		!m.settingsPane.AllowFocusChange() ||
 		!m.sessionsPane.AllowFocusChange() ||
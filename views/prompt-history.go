@@ -0,0 +1,44 @@
+package views
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/tearingItUp786/nekot/components"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const noPromptHistoryMsg = "No prompts sent yet"
+
+// openHistoryPicker lists the prompts on a single line each, the full prompt is kept as the entry id
+func (m *MainView) openHistoryPicker(prompts []string) tea.Cmd {
+	if len(prompts) == 0 {
+		return util.MakeErrorMsg(noPromptHistoryMsg)
+	}
+
+	entries := []components.Command{}
+	for _, prompt := range prompts {
+		entries = append(entries, components.Command{
+			ID:    prompt,
+			Title: strings.Join(strings.Fields(prompt), " "),
+		})
+	}
+
+	m.palette = components.NewCommandPalette(entries, m.getColors())
+	m.palette.SetPlaceholder("Search sent prompts")
+	m.paletteMode = historyPicker
+	m.showPalette = true
+	return m.palette.Init()
+}
+
+// restorePrompt opens the editor for multiline prompts, so that their line breaks are kept
+func (m *MainView) restorePrompt(prompt string) tea.Cmd {
+	m.focused = util.PromptPane
+	cmd := m.resetFocus()
+
+	if strings.Contains(prompt, "\n") {
+		cmd = m.openEditor()
+	}
+	return tea.Sequence(cmd, util.SendPromptHistoryPickedMsg(prompt))
+}
@@ -0,0 +1,117 @@
+package views
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/tearingItUp786/nekot/components"
+	"github.com/tearingItUp786/nekot/snippets"
+	"github.com/tearingItUp786/nekot/util"
+)
+
+const noSnippetsMsg = "No snippets saved yet, use /snippet save <name> <text>"
+
+// openSnippetsPicker reuses the palette, its entries are the snippet names
+func (m *MainView) openSnippetsPicker() tea.Cmd {
+	savedSnippets, err := m.snippetsService.GetSnippets()
+	if err != nil {
+		return util.MakeErrorMsg(err.Error())
+	}
+	if len(savedSnippets) == 0 {
+		return util.MakeErrorMsg(noSnippetsMsg)
+	}
+
+	entries := []components.Command{}
+	for _, snippet := range savedSnippets {
+		entries = append(entries, components.Command{
+			ID:    snippet.Name,
+			Title: snippet.Name,
+			Key:   strings.Join(snippets.GetPlaceholders(snippet.Content), ", "),
+		})
+	}
+
+	m.palette = components.NewCommandPalette(entries, m.getColors())
+	m.palette.SetPlaceholder("Type a snippet name")
+	m.paletteMode = snippetsPicker
+	m.showPalette = true
+	return m.palette.Init()
+}
+
+// useSnippet asks for the placeholders first, snippets without them are inserted right away
+func (m *MainView) useSnippet(name string) tea.Cmd {
+	snippet, err := m.snippetsService.GetSnippet(name)
+	if err != nil {
+		return util.MakeErrorMsg(err.Error())
+	}
+
+	if len(snippets.GetPlaceholders(snippet.Content)) == 0 {
+		return m.insertIntoEditor(snippet.Content)
+	}
+
+	m.snippetForm = components.NewSnippetForm(snippet, m.getColors())
+	m.showSnippetForm = true
+	return m.snippetForm.Init()
+}
+
+// insertIntoEditor opens the editor first, so that the text lands in the textarea
+func (m *MainView) insertIntoEditor(text string) tea.Cmd {
+	return tea.Sequence(m.openEditor(), util.SendPromptInsertRequestedMsg(text))
+}
+
+func (m MainView) handleSnippetFormKeys(msg tea.KeyMsg) (MainView, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch {
+	case m.snippetForm.IsCloseKey(msg):
+		m.showSnippetForm = false
+
+	case m.snippetForm.IsSubmitKey(msg):
+		m.showSnippetForm = false
+		cmd = m.insertIntoEditor(m.snippetForm.GetFilledSnippet())
+
+	default:
+		m.snippetForm, cmd = m.snippetForm.Update(msg)
+	}
+
+	return m, cmd
+}
+
+func (m MainView) saveSnippet(msg snippets.SaveSnippetRequested) tea.Cmd {
+	if err := m.snippetsService.SaveSnippet(msg.Name, msg.Content); err != nil {
+		return util.MakeErrorMsg(err.Error())
+	}
+	return util.SendNotificationMsg(util.SnippetSavedNotification)
+}
+
+func (m MainView) deleteSnippet(msg snippets.DeleteSnippetRequested) tea.Cmd {
+	if err := m.snippetsService.DeleteSnippet(msg.Name); err != nil {
+		return util.MakeErrorMsg(err.Error())
+	}
+	return util.SendNotificationMsg(util.SnippetDeletedNotification)
+}
+
+// snippetFormView takes the place of the chat pane, the same way as the palette
+func (m MainView) snippetFormView() string {
+	colors := m.getColors()
+	w, h := util.CalcChatPaneSize(m.terminalWidth, m.terminalHeight-helpFooterHeight, m.viewMode)
+
+	form := m.snippetForm
+	form.SetWidth(w - 2)
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(colors.MainColor).
+		Render("Snippet: " + form.GetName())
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(colors.ActiveTabBorderColor).
+		MarginRight(util.ChatPaneMarginRight).
+		Padding(0, 1).
+		Width(w).
+		Height(h).
+		MaxHeight(h + paletteBorderHeight).
+		Render(lipgloss.JoinVertical(lipgloss.Left, title, "", form.View()))
+}